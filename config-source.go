@@ -21,11 +21,12 @@ import (
 
 // SourceConfig is the structure for configuring a specific source
 type SourceConfig struct {
-	Name   string             `json:"name"`
-	Parent *string            `json:"parent,omitempty"`
-	Values ConfigSourceValues `json:"values"`
-	Unique [][]string         `json:"unique,omitempty"`
-	Index  [][]string         `json:"index,omitempty"`
+	Name    string             `json:"name"`
+	Parent  *string            `json:"parent,omitempty"`
+	Values  ConfigSourceValues `json:"values"`
+	Unique  [][]string         `json:"unique,omitempty"`
+	Index   [][]string         `json:"index,omitempty"`
+	Filters []string           `json:"filters,omitempty"`
 
 	config *Config
 }
@@ -40,12 +41,17 @@ func (sc *SourceConfig) Clone() (cloned *SourceConfig) {
 	if sc.Parent != nil {
 		parent = values.Ref(*sc.Parent)
 	}
+	var filters []string
+	if sc.Filters != nil {
+		filters = slices.Copy(sc.Filters)
+	}
 	cloned = &SourceConfig{
-		Name:   sc.Name,
-		Parent: parent,
-		Values: sc.Values.Clone(),
-		Unique: slices.Copy(sc.Unique),
-		Index:  slices.Copy(sc.Index),
+		Name:    sc.Name,
+		Parent:  parent,
+		Values:  sc.Values.Clone(),
+		Unique:  slices.Copy(sc.Unique),
+		Index:   slices.Copy(sc.Index),
+		Filters: filters,
 	}
 	return
 }
@@ -137,6 +143,16 @@ func (sc *SourceConfig) AddIndex(keys ...string) *SourceConfig {
 	return sc
 }
 
+// AddFilter adds the given EQL expression to the default filters, which are
+// ANDed into the WITHIN clause of every statement using this source. Source
+// keys without a source name are keys of this source, for example:
+//
+//	.archived == false
+func (sc *SourceConfig) AddFilter(expression string) *SourceConfig {
+	sc.Filters = append(sc.Filters, expression)
+	return sc
+}
+
 // DoneSource completes this SourceConfig builder chain
 func (sc *SourceConfig) DoneSource() *Config {
 	return sc.config
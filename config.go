@@ -20,6 +20,8 @@ import (
 	"strings"
 
 	"github.com/iancoleman/strcase"
+
+	"github.com/go-corelibs/maps"
 )
 
 // Config is the structure for configuring a New EnjinQL instance
@@ -53,6 +55,18 @@ import (
 type Config struct {
 	Prefix  string        `json:"prefix,omitempty"`
 	Sources ConfigSources `json:"sources,omitempty"`
+	// Primary is the name of the primary source, the source of all source
+	// keys without a source name (ie: ".key"), defaults to the first source
+	// when empty
+	Primary string `json:"primary,omitempty"`
+	// QueryKey is the primary source column returned by QUERY statements,
+	// defaults to PageStubKey when empty
+	QueryKey string `json:"queryKey,omitempty"`
+	// Queries are named EQL statements, with named placeholders for the
+	// arguments given to EnjinQL.Run, for example:
+	//
+	//	"recent_by_type": "LOOKUP .url WITHIN .type == {type} ORDER BY .updated DESC LIMIT {n}"
+	Queries map[string]string `json:"queries,omitempty"`
 }
 
 // ParseConfig unmarshalls the given JSON data into a new Config instance
@@ -76,8 +90,13 @@ func NewConfig(prefix ...string) (c *Config) {
 
 func (c *Config) Clone() (cloned *Config) {
 	cloned = &Config{
-		Prefix:  c.Prefix,
-		Sources: c.Sources.Clone(),
+		Prefix:   c.Prefix,
+		Sources:  c.Sources.Clone(),
+		Primary:  c.Primary,
+		QueryKey: c.QueryKey,
+	}
+	if c.Queries != nil {
+		cloned.Queries = maps.CopyBaseType(c.Queries)
 	}
 	cloned.Sources.update(cloned)
 	return
@@ -148,6 +167,44 @@ func (c *Config) Make() (config *Config, err error) {
 	return
 }
 
+// SetPrimary configures the Config.Primary setting
+func (c *Config) SetPrimary(name string) *Config {
+	c.Primary = name
+	return c
+}
+
+// GetPrimary returns the Config.Primary setting, or the name of the first
+// source if empty
+func (c *Config) GetPrimary() (name string) {
+	if name = c.Primary; name == "" && len(c.Sources) > 0 {
+		name = c.Sources[0].Name
+	}
+	return
+}
+
+// SetQueryKey configures the Config.QueryKey setting
+func (c *Config) SetQueryKey(key string) *Config {
+	c.QueryKey = key
+	return c
+}
+
+// GetQueryKey returns the Config.QueryKey setting, or PageStubKey if empty
+func (c *Config) GetQueryKey() (key string) {
+	if key = c.QueryKey; key == "" {
+		key = PageStubKey
+	}
+	return
+}
+
+// AddQuery adds the named EQL statement to the Config.Queries
+func (c *Config) AddQuery(name, statement string) *Config {
+	if c.Queries == nil {
+		c.Queries = make(map[string]string)
+	}
+	c.Queries[name] = statement
+	return c
+}
+
 func (c *Config) AddSource(source *SourceConfig) *Config {
 	c.Sources = append(c.Sources, source)
 	return c
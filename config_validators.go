@@ -16,8 +16,11 @@ package enjinql
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/iancoleman/strcase"
+
+	"github.com/go-corelibs/maps"
 )
 
 type cConfigValidator struct {
@@ -51,6 +54,45 @@ var (
 					return
 				},
 			},
+			{
+				"any primary must be a snake cased source name",
+				func(c *Config) (err error) {
+					if c.Primary != "" {
+						if err = mustSnakeCase(c.Primary); err != nil {
+							return
+						}
+						for _, sc := range c.Sources {
+							if sc.Name == c.Primary {
+								return
+							}
+						}
+						err = fmt.Errorf("%w: %w (%q)", ErrInvalidConfig, ErrPrimaryNotFound, c.Primary)
+					}
+					return
+				},
+			},
+			{
+				"any query key must be snake cased",
+				func(c *Config) (err error) {
+					if c.QueryKey != "" {
+						err = mustSnakeCase(c.QueryKey)
+					}
+					return
+				},
+			},
+			{
+				"any queries must be snake cased names of statements",
+				func(c *Config) (err error) {
+					for _, name := range maps.SortedKeys(c.Queries) {
+						if err = mustSnakeCase(name); err != nil {
+							return
+						} else if strings.TrimSpace(c.Queries[name]) == "" {
+							return fmt.Errorf("%w: %w (%q)", ErrInvalidConfig, ErrInvalidQuery, name)
+						}
+					}
+					return
+				},
+			},
 			{
 				"must have at least one source",
 				func(c *Config) (err error) {
@@ -98,6 +140,17 @@ var (
 					return
 				},
 			},
+			{
+				"any filters must be valid expressions",
+				func(c *Config, idx int, sc *SourceConfig) (err error) {
+					for jdx, filter := range sc.Filters {
+						if _, err = parseFilter(sc.Name, filter); err != nil {
+							return fmt.Errorf("%w: %w (%q filter #%d) - %w", ErrInvalidConfig, ErrInvalidFilter, sc.Name, jdx+1, err)
+						}
+					}
+					return
+				},
+			},
 		},
 		values: []cSourceConfigValueValidator{
 			{
@@ -15,38 +15,66 @@
 package enjinql
 
 import (
+	"fmt"
+
 	"github.com/go-corelibs/go-sqlbuilder"
 	"github.com/go-corelibs/values"
 )
 
+// expandMarkers replaces all time bucket, JSON path and seeded RANDOM()
+// placeholders in the query with the dialect specific SQL, buckets first as
+// these can wrap JSON path placeholders
+func expandMarkers(dialect sqlbuilder.Dialect, query string) (expanded string, err error) {
+	if expanded, err = expandBuckets(dialect, query); err != nil {
+		return
+	} else if expanded, err = expandJsonPaths(dialect, expanded); err != nil {
+		return
+	}
+	expanded, err = expandRandom(dialect, expanded)
+	return
+}
+
+const (
+	// GroupCountKey is the column alias for the counts of LOOKUP COUNT
+	// statements with a GROUP BY clause
+	GroupCountKey = "count"
+)
+
 func (eql *enjinql) prepareSyntaxBuild(syntax *Syntax) (state *cProcessor, err error) {
 	if err = syntax.Validate(); err != nil {
 		return
 	}
 
 	if syntax.Query {
-		// primary source "stub" is always the query context key
-		// TODO: make the query stub context key configurable somehow
+		// the primary source query key is always the first query context key,
+		// followed by any included context keys
 		if primarySource, ok := eql.sources.getPrimarySource(); ok {
-			syntax.Keys = []*SourceKey{{
+			syntax.Keys = append([]*SourceKey{{
 				Source: values.Ref(primarySource.name),
-				Key:    PageStubKey,
+				Key:    eql.config.GetQueryKey(),
 				Alias:  nil,
 				Pos:    syntax.Pos,
-			}}
+			}}, syntax.Include...)
 		}
 	}
 
+	// the default filters are added before planning so that the sources they
+	// use are joined
+	if syntax, err = eql.applyFilters(syntax); err != nil {
+		return
+	}
+
 	state = &cProcessor{
 		builder: eql.builder,
 		syntax:  syntax,
 		sources: eql.sources,
 		tables:  make(map[string]sqlbuilder.Table),
 		updated: make(map[string]*cProcessSrcKey),
+
+		namespaces: eql.getNamespaceSources,
 	}
 
-	//var order []string
-	if _ /*order*/, state.updated, err = state.findUpdatedSrcKeyRefs(); err != nil {
+	if state.order, state.updated, err = state.findUpdatedSrcKeyRefs(); err != nil {
 		return
 	}
 
@@ -64,6 +92,12 @@ func (eql *enjinql) preparePlan(syntax *Syntax) (plan *gSourcePlan, err error) {
 }
 
 func (eql *enjinql) prepareSQL(syntax *Syntax) (sql string, argv []interface{}, err error) {
+	if syntax.Facets {
+		// each facet key is a separate query, see prepareFacetSQL
+		err = ErrFacetsStatement
+		return
+	}
+
 	var state *cProcessor
 	if state, err = eql.prepareSyntaxBuild(syntax); err != nil {
 		return
@@ -79,27 +113,48 @@ func (eql *enjinql) prepareSQL(syntax *Syntax) (sql string, argv []interface{},
 
 	state.build = eql.builder.Select(top)
 
-	getColumn := func(sk *SourceKey) (column sqlbuilder.Column, alias string, ok bool) {
-		var bsk *cProcessSrcKey
-		if ok = sk.Alias != nil; ok {
-			if bsk, ok = state.updated[*sk.Alias]; ok {
-				column = bsk.c
-				alias = *sk.Alias
-				return
+	getColumn := state.getKeyColumn
+
+	// joining one-to-many sources repeats the rows selected, collapse these
+	// back down to one row per key source row unless ALL was requested
+	grouped := state.syntax.GroupBy != nil
+	dedupe := !grouped && !state.syntax.All && !state.syntax.Distinct && state.fansOut()
+
+	// ordering by the keys of the sources repeating the rows has more than
+	// one value for each row, the rows are grouped instead of DISTINCT
+	collapse := dedupe && !state.syntax.Count && state.ordersFannedOut()
+	if collapse && (state.syntax.After != nil || state.syntax.Per != nil) {
+		err = newSyntaxError(state.syntax.OrderBy.Pos, ErrInvalidSyntax, ErrOrderByFanOut)
+		return
+	}
+
+	if (dedupe && !state.syntax.Count) || state.syntax.Per != nil {
+		// the inner query selects the distinct rows and the outer query
+		// orders and limits them
+		state.wrap = &cWrapSelect{grouped: collapse}
+	}
+
+	var selected []sqlbuilder.Column
+
+	// selectKey selects the key column given, which wrapped statements alias
+	// within the inner query and name within the outer query
+	selectKey := func(column sqlbuilder.Column, alias, name string) {
+		if state.wrap != nil {
+			if alias == "" {
+				alias = name
 			}
-		} else if bsk, ok = state.updated[sk.String()]; ok {
-			column = bsk.c
+			selected = append(selected, state.wrap.addKey(column, alias))
 			return
+		} else if alias != "" {
+			column = column.As(alias)
 		}
-		return
+		selected = append(selected, column)
 	}
 
 	if state.syntax.Lookup {
 		// lookup <columns> within <expression> order...
 		// select <columns> from <table> <joins> where <expression> order by <expression> offset <int> limit <int>
 
-		var columns []sqlbuilder.Column
-
 		// syntax.Validate ensures specifically one column present for COUNT and DISTINCT statements
 		switch {
 		case state.syntax.Count && state.syntax.Distinct:
@@ -108,47 +163,52 @@ func (eql *enjinql) prepareSQL(syntax *Syntax) (sql string, argv []interface{},
 					"COUNT",
 					sqlbuilder.Func("DISTINCT", c),
 				)
-				if alias != "" {
-					columns = []sqlbuilder.Column{fn.As(alias)}
-				} else {
-					columns = []sqlbuilder.Column{fn}
+				selectKey(fn, alias, "")
+			}
+		case state.syntax.Count && grouped:
+			// count the key source rows of each group
+			for _, sk := range state.syntax.Keys {
+				if column, alias, ok := getColumn(sk); ok {
+					selectKey(column, alias, "")
 				}
 			}
+			var fn sqlbuilder.Column
+			if fn, err = state.makeGroupCount(); err != nil {
+				return
+			}
+			selected = append(selected, fn.As(GroupCountKey))
 		case state.syntax.Count:
 			if c, alias, ok := getColumn(state.syntax.Keys[0]); ok {
 				fn := sqlbuilder.Func("COUNT", c)
-				if alias != "" {
-					columns = []sqlbuilder.Column{fn.As(alias)}
-				} else {
-					columns = []sqlbuilder.Column{fn}
+				if dedupe {
+					// the one key counted is of the one key source, count
+					// the distinct rows of that source
+					var t sqlbuilder.Table
+					if t, err = state.getKeySources()[0].getTable(); err != nil {
+						return
+					}
+					fn = sqlbuilder.Func(
+						"COUNT",
+						sqlbuilder.Func("DISTINCT", t.C(SourceIdKey)),
+					)
 				}
+				selectKey(fn, alias, "")
 			}
 		case state.syntax.Distinct:
 			if c, alias, ok := getColumn(state.syntax.Keys[0]); ok {
-				fn := sqlbuilder.Func("DISTINCT", c)
-				if alias != "" {
-					columns = []sqlbuilder.Column{fn.As(alias)}
-				} else {
-					columns = []sqlbuilder.Column{fn}
-				}
+				selectKey(sqlbuilder.Func("DISTINCT", c), alias, "")
 			}
 		default:
 			for _, sk := range state.syntax.Keys {
 				if column, alias, ok := getColumn(sk); ok {
-					if alias != "" {
-						columns = append(columns, column.As(alias))
-						continue
-					}
-					columns = append(columns, column)
+					selectKey(column, alias, state.updated[sk.lookup()].u.Key)
 				}
 			}
 		}
 
-		state.build.Columns(columns...)
-
 	} else if state.syntax.Query {
-		// query within <expression> order...
-		// select <page>.stub from <page> <joins> where <expression> order by <expression> offset <int> limit <int>
+		// query within <expression> include <keys>... order...
+		// select <page>.stub, <keys>... from <page> <joins> where <expression> order by <expression> offset <int> limit <int>
 
 		var ok bool
 		var source *cSource
@@ -156,9 +216,8 @@ func (eql *enjinql) prepareSQL(syntax *Syntax) (sql string, argv []interface{},
 		if source, ok = eql.sources.getSource(primarySourceName); ok {
 			if t, err = source.getTable(); err != nil {
 				return
-			} else if stub := t.C(PageStubKey); stub != nil {
-				// TODO: need a means of specifying the "stub" column in a source config so that non PageSource setups can work
-				state.build.Columns(stub)
+			} else if stub := t.C(eql.config.GetQueryKey()); !sqlbuilder.IsColumnError(stub) {
+				selectKey(stub, "", eql.config.GetQueryKey())
 			} else {
 				err = ErrQueryRequiresStub
 				return
@@ -168,16 +227,107 @@ func (eql *enjinql) prepareSQL(syntax *Syntax) (sql string, argv []interface{},
 			return
 		}
 
+		for _, sk := range state.syntax.Include {
+			if column, alias, ok := getColumn(sk); ok {
+				selectKey(column, alias, state.updated[sk.lookup()].u.Key)
+			}
+		}
+
 	} // state.prepareBuild already validated the !Lookup && !Query case
 
+	// keyset pagination selects the ORDER BY and id values of each row so
+	// that the last row of a page can be encoded as the next cursor
+	var cursorColumns []sqlbuilder.Column
+	if state.syntax.After != nil {
+		if cursorColumns, err = state.prepareCursorColumns(); err != nil {
+			return
+		}
+		for idx, column := range cursorColumns {
+			selected = append(selected, column.As(makeCursorKey(idx)))
+			if state.wrap != nil {
+				state.wrap.pass(makeCursorKey(idx))
+			}
+		}
+	}
+
+	if state.syntax.Per != nil {
+		// top-N per group selects the PER key of each row, partitioning the
+		// rows which the outer query ranks by the ORDER BY values
+		var per sqlbuilder.Column
+		if per, err = state.syntax.Per.make(state); err != nil {
+			return
+		}
+		state.wrap.per = state.wrap.add(per)
+	}
+
+	if dedupe && !state.syntax.Count {
+		// the key source ids of each row make the selected rows distinct
+		var ids []sqlbuilder.Column
+		for _, source := range state.getKeySources() {
+			var t sqlbuilder.Table
+			if t, err = source.getTable(); err != nil {
+				return
+			}
+			ids = append(ids, t.C(SourceIdKey))
+			state.wrap.add(t.C(SourceIdKey))
+		}
+		if collapse {
+			state.build.GroupBy(ids...)
+		} else {
+			state.build.Distinct()
+		}
+	}
+
+	var conditions []sqlbuilder.Condition
+
 	if state.syntax.Within != nil {
 
 		var cond sqlbuilder.Condition
 		if cond, err = state.syntax.Within.make(state); err != nil {
 			return
 		}
-		state.build.Where(cond)
+		conditions = append(conditions, cond)
+
+	}
+
+	desc := state.syntax.OrderBy != nil && state.syntax.OrderBy.IsDESC()
+
+	if state.syntax.After != nil {
+
+		var cursor string
+		if cursor, err = state.getAfterCursor(); err != nil {
+			return
+		} else if cursor != "" {
+			var list []interface{}
+			var cond sqlbuilder.Condition
+			if list, err = decodeCursor(cursor); err != nil {
+				return
+			} else if cond, err = state.makeKeysetCondition(desc, cursorColumns, list); err != nil {
+				return
+			}
+			conditions = append(conditions, cond)
+		}
+
+	}
+
+	switch len(conditions) {
+	case 0:
+	case 1:
+		state.build.Where(conditions[0])
+	default:
+		state.build.Where(sqlbuilder.And(conditions...))
+	}
 
+	if grouped {
+		var columns []sqlbuilder.Column
+		for _, srcRef := range *state.syntax.GroupBy {
+			var column sqlbuilder.Column
+			if column, err = srcRef.make(state); err != nil {
+				return
+			}
+			columns = append(columns, column)
+		}
+		state.build.GroupBy(columns...)
 	}
 
 	if state.syntax.OrderBy != nil {
@@ -186,6 +336,39 @@ func (eql *enjinql) prepareSQL(syntax *Syntax) (sql string, argv []interface{},
 		}
 	}
 
+	if seeded := state.syntax.OrderBy != nil && state.syntax.OrderBy.IsSeeded(); !seeded {
+		// the key source id is the final tie-breaker, keeping the order stable
+		if state.syntax.After != nil {
+			state.orderBy(desc, cursorColumns[len(cursorColumns)-1])
+		} else if state.syntax.Per != nil {
+			sources := state.getKeySources()
+			if len(sources) == 0 {
+				err = fmt.Errorf("%w: PER requires at least one source key", ErrInvalidSyntax)
+				return
+			}
+			var t sqlbuilder.Table
+			if t, err = sources[0].getTable(); err != nil {
+				return
+			}
+			state.orderBy(desc, t.C(SourceIdKey))
+		}
+	}
+
+	if state.wrap != nil {
+		state.build.Columns(append(selected, state.wrap.columns...)...)
+		if sql, argv, err = state.build.ToSql(); err != nil {
+			return
+		} else if state.syntax.Per != nil {
+			sql, argv = state.wrap.makePer(eql.dialect, sql, argv, *state.syntax.Limit, state.syntax.maxRows, state.syntax.skipRows)
+		} else {
+			sql = state.wrap.make(eql.dialect, sql, state.syntax.Limit, state.syntax.Offset)
+		}
+		sql, err = expandMarkers(eql.dialect, sql)
+		return
+	}
+
+	state.build.Columns(selected...)
+
 	if state.syntax.Offset != nil {
 		state.build.Offset(*state.syntax.Offset)
 	}
@@ -194,5 +377,8 @@ func (eql *enjinql) prepareSQL(syntax *Syntax) (sql string, argv []interface{},
 		state.build.Limit(*state.syntax.Limit)
 	}
 
-	return state.build.ToSql()
+	if sql, argv, err = state.build.ToSql(); err == nil {
+		sql, err = expandMarkers(eql.dialect, sql)
+	}
+	return
 }
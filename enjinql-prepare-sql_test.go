@@ -0,0 +1,137 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+)
+
+func TestFanOutDeduplication(t *testing.T) {
+	Convey("fan-out de-duplication", t, func() {
+
+		eql, _ := makeQfEQL()
+		defer eql.Close()
+
+		_, results, err := eql.Perform(`LOOKUP .Shasum WITHIN word.Word ^= "th" ORDER BY .Shasum`)
+		SoMsg("lookup error", err, ShouldBeNil)
+		SoMsg("lookup results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "0102000405"},
+			{"shasum": "1122334455"},
+		})
+
+		_, results, err = eql.Perform(`LOOKUP .Shasum WITHIN word.Word ^= "th" ORDER BY .Shasum DESC OFFSET 1 LIMIT 1`)
+		SoMsg("lookup ordered error", err, ShouldBeNil)
+		SoMsg("lookup ordered results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "0102000405"},
+		})
+
+		_, results, err = eql.Perform(`LOOKUP .Shasum WITHIN word.Word ^= "th" ORDER BY RANDOM()`)
+		SoMsg("lookup random error", err, ShouldBeNil)
+		SoMsg("lookup random results", len(results), ShouldEqual, 2)
+
+		_, results, next, err := eql.PerformPage("", `LOOKUP .Shasum WITHIN word.Word ^= "th" ORDER BY .Shasum LIMIT 1`)
+		SoMsg("lookup first page error", err, ShouldBeNil)
+		SoMsg("lookup first page results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "0102000405"},
+		})
+		_, results, next, err = eql.PerformPage(next, `LOOKUP .Shasum WITHIN word.Word ^= "th" ORDER BY .Shasum LIMIT 1`)
+		SoMsg("lookup last page error", err, ShouldBeNil)
+		SoMsg("lookup last page next", next, ShouldEqual, "")
+		SoMsg("lookup last page results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "1122334455"},
+		})
+
+		_, results, err = eql.Perform(`LOOKUP .Shasum WITHIN word.Word ^= "th" ORDER BY word.Word`)
+		SoMsg("lookup ordered by word error", err, ShouldBeNil)
+		SoMsg("lookup ordered by word results", len(results), ShouldEqual, 2)
+		_, results, err = eql.Perform(`LOOKUP .Shasum ORDER BY word.Word DESC`)
+		SoMsg("lookup ordered by all words error", err, ShouldBeNil)
+		SoMsg("lookup ordered by all words results", len(results), ShouldEqual, 2)
+		_, _, _, err = eql.PerformPage("", `LOOKUP .Shasum WITHIN word.Word ^= "th" ORDER BY word.Word LIMIT 1`)
+		SoMsg("lookup page ordered by word error", fmt.Sprint(err), ShouldContainSubstring, ErrOrderByFanOut.Error())
+		_, _, _, err = eql.PerformPage("", `LOOKUP ALL .Shasum WITHIN word.Word ^= "th" ORDER BY word.Word LIMIT 1`)
+		SoMsg("lookup all page ordered by word error", err, ShouldBeNil)
+
+		query, _, err := eql.ToSQL(`LOOKUP .Shasum WITHIN word.Word ^= "th"`)
+		SoMsg("lookup query error", err, ShouldBeNil)
+		SoMsg("lookup query distinct", query, ShouldContainSubstring, ` SELECT DISTINCT `)
+		SoMsg("lookup query group by", query, ShouldNotContainSubstring, ` GROUP BY `)
+
+		_, results, err = eql.Perform(`LOOKUP ALL .Shasum WITHIN word.Word ^= "th"`)
+		SoMsg("lookup all error", err, ShouldBeNil)
+		SoMsg("lookup all results", len(results), ShouldEqual, 4)
+
+		_, results, err = eql.Perform(`LOOKUP COUNT .Shasum AS total WITHIN word.Word ^= "th"`)
+		SoMsg("lookup count error", err, ShouldBeNil)
+		SoMsg("lookup count results", results, ShouldEqual, clContext.Contexts{
+			{"total": int64(2)},
+		})
+
+	})
+}
+
+func TestQueryKeyInclude(t *testing.T) {
+	Convey("query key and include", t, func() {
+
+		config, err := NewConfig("kb").
+			SetQueryKey("body").
+			NewSource("article").
+			NewStringValue("slug", 64).
+			NewStringValue("body", -1).
+			DoneSource().
+			Make()
+		SoMsg("config error", err, ShouldBeNil)
+		SoMsg("config query key", config.GetQueryKey(), ShouldEqual, "body")
+		SoMsg("cloned query key", config.Clone().GetQueryKey(), ShouldEqual, "body")
+		SoMsg("default query key", NewConfig("kb").GetQueryKey(), ShouldEqual, PageStubKey)
+
+		eql, tdb := makeTestEQL(config)
+		defer tdb.Close()
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		_, err = tx.Insert("article", "first", "the first article")
+		SoMsg("insert first error", err, ShouldBeNil)
+		_, err = tx.Insert("article", "second", "the second article")
+		SoMsg("insert second error", err, ShouldBeNil)
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err := eql.Perform(`QUERY WITHIN .Slug == "second"`)
+		SoMsg("query error", err, ShouldBeNil)
+		SoMsg("query results", results, ShouldEqual, clContext.Contexts{
+			{"body": "the second article"},
+		})
+
+		columns, results, err := eql.Perform(`QUERY INCLUDE .Slug, .ID AS number ORDER BY .Slug DESC`)
+		SoMsg("include error", err, ShouldBeNil)
+		SoMsg("include columns", columns, ShouldEqual, []string{"body", "slug", "number"})
+		SoMsg("include results", results, ShouldEqual, clContext.Contexts{
+			{"body": "the second article", "slug": "second", "number": int64(2)},
+			{"body": "the first article", "slug": "first", "number": int64(1)},
+		})
+
+		_, _, err = eql.Perform(`LOOKUP .Slug INCLUDE .Body`)
+		SoMsg("lookup include error", err, ShouldNotBeNil)
+
+		_, err = NewConfig("kb").SetQueryKey("Body").AddSource(PageSourceConfig()).Make()
+		SoMsg("invalid query key error", err, ShouldNotBeNil)
+
+	})
+}
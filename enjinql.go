@@ -15,9 +15,11 @@
 package enjinql
 
 import (
+	sqlContext "context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"sync"
 
 	"github.com/go-corelibs/context"
@@ -40,11 +42,64 @@ type EnjinQL interface {
 	// Perform uses ToSQL to build and execute the SQL statement
 	Perform(format string, argv ...interface{}) (columns []string, results context.Contexts, err error)
 
+	// Run performs the named Config.Queries statement, with the arguments
+	// given for the named placeholders
+	Run(name string, args map[string]interface{}) (columns []string, results context.Contexts, err error)
+
+	// RunFacets is like Run for named Config.Queries FACETS statements
+	RunFacets(name string, args map[string]interface{}) (facets []*Facet, err error)
+
+	// CheckPolicy returns a PolicyError for the first violation of the Policy
+	// given by the parsed Syntax, see Policy. The parsed Syntax is not
+	// modified
+	CheckPolicy(policy *Policy, parsed *Syntax) (err error)
+
+	// ParseSandboxed is like Parse and also uses CheckPolicy to enforce the
+	// Policy given, for untrusted end-user input. The Syntax returned has the
+	// Policy.MaxLimit applied
+	ParseSandboxed(policy *Policy, format string, args ...interface{}) (parsed *Syntax, err error)
+
+	// PerformSandboxed is like Perform and uses ParseSandboxed to enforce the
+	// Policy given, for untrusted end-user input
+	PerformSandboxed(policy *Policy, format string, argv ...interface{}) (columns []string, results context.Contexts, err error)
+
+	// PerformPage is like Perform for keyset pagination, returning the page
+	// of results following the given cursor along with the next cursor. An
+	// empty cursor is the first page and an empty next cursor means there
+	// are no more pages. The cursor given replaces any AFTER clause present
+	// in the statement, which should have a LIMIT to page at all
+	PerformPage(cursor, format string, argv ...interface{}) (columns []string, results context.Contexts, next string, err error)
+
+	// Paginate uses Parse to prepare the Syntax tree and then, within one
+	// read transaction, counts the total number of results and performs the
+	// query for the requested page number (starting from one) of perPage
+	// results. The LIMIT of statements using LIMIT n PER <key> is kept as
+	// the limit of each PER key value and the pages are of all those rows
+	Paginate(format string, page, perPage int, argv ...interface{}) (paginated *Paginated, err error)
+
+	// Facets performs a FACETS statement for the given source keys, with an
+	// optional WITHIN expression, see PerformFacets
+	Facets(within string, keys []string, argv ...interface{}) (facets []*Facet, err error)
+
+	// PerformFacets uses Parse to prepare a FACETS statement and returns the
+	// histogram of values for each of the source keys, using the WITHIN
+	// expression to constrain all of them. The LIMIT is per-key, with the
+	// remaining counts summed into the Facet.Other bucket
+	PerformFacets(format string, argv ...interface{}) (facets []*Facet, err error)
+
 	// Plan uses Parse to prepare the Syntax tree, then prepares the SQL table
 	// INNER JOIN statement plan and returns two summaries of the resulting
 	// plan: a brief one-liner and a verbose multi-line
 	Plan(format string, args ...interface{}) (brief, verbose string, err error)
 
+	// CollectGarbage deletes, in batches, the rows of the named data sources
+	// which are no longer referenced by any linked values, along with any rows
+	// depending on them, and returns the number of rows collected per source.
+	// All data sources referenced by linked values are collected when no
+	// names are given. With dryRun, nothing is deleted and the counts are of
+	// the rows which would have been collected
+	CollectGarbage(ctx sqlContext.Context, dryRun bool, sources ...string) (collected map[string]int64, err error)
+
 	// DBH returns either the current sql.Tx or the default sql.DB instance
 	DBH() SqlDB
 
@@ -90,7 +145,9 @@ type EnjinQL interface {
 	CreateIndexes() (err error)
 
 	// Close calls the Close method on the sql.DB instance and flags this
-	// enjinql instance as being closed
+	// enjinql instance as being closed. EnjinQL instances created by a
+	// Namespaces manager are removed from it instead, leaving the shared
+	// sql.DB open
 	Close() (err error)
 
 	// Ready returns nil if this EnjinQL instance has an open sql.DB instance
@@ -110,6 +167,7 @@ type enjinql struct {
 	builder sqlbuilder.Buildable
 
 	sources *cSources
+	filters map[string][]*Expression
 
 	m *sync.RWMutex
 }
@@ -119,6 +177,10 @@ type Option func(o *option) (err error)
 type option struct {
 	skipCreateTables  bool
 	skipCreateIndexes bool
+	foreignKeys       bool
+
+	namespaces *cNamespaces
+	filters    []cFilter
 }
 
 func SkipCreateTable(o *option) (err error) {
@@ -131,6 +193,15 @@ func SkipCreateIndex(o *option) (err error) {
 	return
 }
 
+// CascadeForeignKeys is an Option for creating tables with FOREIGN KEY ... ON
+// DELETE CASCADE constraints for all parent and linked values, so that the
+// database removes dependent rows instead of SqlTX.DeleteCascade. Note that
+// SQLite only enforces foreign keys with: PRAGMA foreign_keys = ON
+func CascadeForeignKeys(o *option) (err error) {
+	o.foreignKeys = true
+	return
+}
+
 func New(c *Config, dbh *sql.DB, dialect sqlbuilder.Dialect, options ...Option) (eql EnjinQL, err error) {
 	if c == nil {
 		err = fmt.Errorf("config is required")
@@ -166,7 +237,7 @@ func New(c *Config, dbh *sql.DB, dialect sqlbuilder.Dialect, options ...Option)
 }
 
 func (eql *enjinql) init() (err error) {
-	eql.sources = newSources(eql.config.Prefix, eql.builder)
+	eql.sources = newSources(eql.config.Prefix, eql.config.Primary, eql.builder)
 	for _, sc := range eql.config.Sources {
 		if err = eql.sources.addSource(sc); err != nil {
 			err = fmt.Errorf("add source error: %w", err)
@@ -174,6 +245,14 @@ func (eql *enjinql) init() (err error) {
 		}
 	}
 
+	if err = eql.initFilters(); err != nil {
+		return
+	}
+
+	if err = eql.initQueries(); err != nil {
+		return
+	}
+
 	if !eql.option.skipCreateTables {
 		if err = eql.CreateTables(); err != nil {
 			return
@@ -193,6 +272,11 @@ func (eql *enjinql) Close() (err error) {
 	defer eql.m.Unlock()
 	if !eql.closed {
 		eql.closed = true
+		if eql.option.namespaces != nil {
+			// the sql.DB is shared with the other namespaces
+			eql.option.namespaces.remove(eql.config.Prefix)
+			return
+		}
 		err = eql.db.db.Close()
 	}
 	return
@@ -237,7 +321,7 @@ func (eql *enjinql) CreateTables() (err error) {
 				err = fmt.Errorf("%w: %q", ErrTableNotFound, source.formal())
 				return
 
-			} else if query, argv, err = eql.builder.CreateTable(t).IfNotExists().ToSql(); err != nil {
+			} else if query, argv, err = eql.createTable(source, t).IfNotExists().ToSql(); err != nil {
 				_ = tx.Rollback()
 				err = fmt.Errorf("%w: %q - %w", ErrCreateTableSQL, source.formal(), err)
 				return
@@ -341,7 +425,11 @@ func (eql *enjinql) Parse(format string, args ...interface{}) (parsed *Syntax, e
 	defer eql.m.RUnlock()
 	var prepared string
 	if prepared, err = PrepareSyntax(format, args...); err == nil && prepared != "" {
-		parsed, err = ParseSyntax(prepared)
+		if parsed, err = ParseSyntax(prepared); err != nil {
+			if _, ee := ParseModify(prepared); ee == nil {
+				err = ErrModifyStatement
+			}
+		}
 		return
 	} else if err != nil {
 		return
@@ -395,6 +483,82 @@ func (eql *enjinql) Perform(format string, argv ...interface{}) (columns []strin
 	return
 }
 
+func (eql *enjinql) PerformPage(cursor, format string, argv ...interface{}) (columns []string, results context.Contexts, next string, err error) {
+	return eql.performPage(eql.withDB, cursor, format, argv...)
+}
+
+// performPage is PerformPage with the queries performed by the run func given
+func (eql *enjinql) performPage(run func(fn func(db SqlDB) (err error)) (err error), cursor, format string, argv ...interface{}) (columns []string, results context.Contexts, next string, err error) {
+	if err = eql.Ready(); err == nil {
+		var parsed *Syntax
+		if parsed, err = eql.Parse(format, argv...); err != nil {
+			return
+		}
+
+		if cursor != "" || parsed.After == nil {
+			text := strconv.Quote(cursor)
+			parsed.After = &Value{Text: &text, Pos: parsed.Pos}
+		}
+
+		// fetch one more than the limit to know if there is a next page
+		var limit int
+		if parsed.Limit != nil && *parsed.Limit > 0 {
+			limit = *parsed.Limit
+			extra := limit + 1
+			parsed.Limit = &extra
+		}
+
+		var query string
+		var args []interface{}
+		if query, args, err = eql.ParsedToSql(parsed); err != nil {
+			return
+		}
+
+		var found []string
+		var rows context.Contexts
+		if err = run(func(db SqlDB) (err error) {
+			var queried *sql.Rows
+			if queried, err = db.Query(query, args...); err == nil {
+				found, rows = scanContexts(queried)
+			}
+			return
+		}); err != nil {
+			return
+		}
+
+		for _, name := range found {
+			if !isCursorKey(name) {
+				columns = append(columns, name)
+			}
+		}
+
+		var more bool
+		if more = limit > 0 && len(rows) > limit; more {
+			rows = rows[:limit]
+		}
+
+		var last []interface{}
+		for _, row := range rows {
+			last = nil
+			for idx := 0; ; idx++ {
+				key := makeCursorKey(idx)
+				value, present := row[key]
+				if !present {
+					break
+				}
+				last = append(last, value)
+				delete(row, key)
+			}
+			results = append(results, row)
+		}
+
+		if more {
+			next, err = encodeCursor(last)
+		}
+	}
+	return
+}
+
 func (eql *enjinql) DBH() SqlDB {
 	return eql.db
 }
@@ -431,27 +595,57 @@ func (eql *enjinql) SqlQuery(query string, argv ...interface{}) (columns []strin
 	if err = eql.Ready(); err == nil {
 		var rows *sql.Rows
 		if rows, err = eql.db.Query(query, argv...); err == nil {
+			columns, results = scanContexts(rows)
+		}
+	}
+	return
+}
 
-			for rows.Next() {
-				var values []interface{}
-				if len(columns) == 0 {
-					columns, _ = rows.Columns()
-				}
-				for range columns {
-					var v interface{} = nil
-					values = append(values, &v)
-				}
-				_ = rows.Scan(values...) // safe to ignore because there are no scanner values
-				row := context.New()
-				for idx, name := range columns {
-					if v, ok := values[idx].(*interface{}); ok {
-						row[name] = *v
-					}
-				}
-				results = append(results, row)
-			}
+// withDB runs fn with the database handle, under the read lock
+func (eql *enjinql) withDB(fn func(db SqlDB) (err error)) (err error) {
+	eql.m.RLock()
+	defer eql.m.RUnlock()
+	return fn(eql.db)
+}
+
+// withReadTX runs fn within a new read-only transaction, under the read lock,
+// for the queries which must see the same state of the database
+func (eql *enjinql) withReadTX(fn func(db SqlDB) (err error)) (err error) {
+	eql.m.RLock()
+	defer eql.m.RUnlock()
 
+	var tx *sql.Tx
+	if tx, err = eql.db.db.BeginTx(sqlContext.Background(), &sql.TxOptions{ReadOnly: true}); err != nil {
+		return
+	}
+	defer func() {
+		_ = tx.Rollback() // nothing to commit
+	}()
+
+	return fn(&cSqlTX{tx: tx, eql: eql})
+}
+
+// scanContexts reads all the given rows into a list of contexts and closes
+// the rows when done
+func scanContexts(rows *sql.Rows) (columns []string, results context.Contexts) {
+	defer rows.Close()
+	for rows.Next() {
+		var values []interface{}
+		if len(columns) == 0 {
+			columns, _ = rows.Columns()
+		}
+		for range columns {
+			var v interface{} = nil
+			values = append(values, &v)
+		}
+		_ = rows.Scan(values...) // safe to ignore because there are no scanner values
+		row := context.New()
+		for idx, name := range columns {
+			if v, ok := values[idx].(*interface{}); ok {
+				row[name] = *v
+			}
 		}
+		results = append(results, row)
 	}
 	return
 }
@@ -0,0 +1,77 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+)
+
+const (
+	// gBucketMarker prefixes the placeholder function names used for time
+	// buckets, see expandBuckets
+	gBucketMarker = "EQL_BUCKET_"
+)
+
+var (
+	// gBucketFormats are the dialect specific time bucket SQL expressions
+	gBucketFormats = map[string]map[string]string{
+		"sqlite3": {
+			"DAY":   `strftime('%%Y-%%m-%%d', %s)`,
+			"MONTH": `strftime('%%Y-%%m', %s)`,
+			"YEAR":  `strftime('%%Y', %s)`,
+		},
+		"postgresql": {
+			"DAY":   `date_trunc('day', %s)`,
+			"MONTH": `date_trunc('month', %s)`,
+			"YEAR":  `date_trunc('year', %s)`,
+		},
+		"mysql": {
+			"DAY":   `DATE_FORMAT(%s, '%%Y-%%m-%%d')`,
+			"MONTH": `DATE_FORMAT(%s, '%%Y-%%m')`,
+			"YEAR":  `DATE_FORMAT(%s, '%%Y')`,
+		},
+	}
+
+	gBucketPattern = regexp.MustCompile(gBucketMarker + `(DAY|MONTH|YEAR)\(((?:[^()]|\([^()]*\))*)\)`)
+)
+
+// makeBucket returns a placeholder function wrapping the given column, which
+// expandBuckets replaces with the dialect specific SQL. sqlbuilder functions
+// only accept column arguments and the bucket formats need string literals
+func makeBucket(bucket string, column sqlbuilder.Column) sqlbuilder.Column {
+	return sqlbuilder.Func(gBucketMarker+strings.ToUpper(bucket), column)
+}
+
+// expandBuckets replaces all time bucket placeholders in the query with the
+// dialect specific SQL
+func expandBuckets(dialect sqlbuilder.Dialect, query string) (expanded string, err error) {
+	if !strings.Contains(query, gBucketMarker) {
+		return query, nil
+	}
+	formats, ok := gBucketFormats[dialect.Name()]
+	if !ok {
+		err = fmt.Errorf("%w: %q", ErrBucketDialect, dialect.Name())
+		return
+	}
+	expanded = gBucketPattern.ReplaceAllStringFunc(query, func(match string) string {
+		m := gBucketPattern.FindStringSubmatch(match)
+		return fmt.Sprintf(formats[m[1]], m[2])
+	})
+	return
+}
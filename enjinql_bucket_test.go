@@ -0,0 +1,126 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+	"github.com/go-corelibs/go-sqlbuilder"
+	"github.com/go-corelibs/go-sqlbuilder/dialects"
+)
+
+func TestTimeBuckets(t *testing.T) {
+	Convey("time buckets", t, func() {
+
+		config, _ := NewConfig("be_eql").
+			AddSource(PageSourceConfig()).
+			Make()
+		eql, tdb := makeTestEQL(config)
+		defer tdb.Close()
+
+		march, _ := time.Parse("2006-01-02 15:04", "2024-03-17 11:25")
+		later, _ := time.Parse("2006-01-02 15:04", "2024-03-30 23:59")
+		april, _ := time.Parse("2006-01-02 15:04", "2024-04-01 00:00")
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		for idx, values := range [][]interface{}{
+			{"1234567890", "en", "page", "", march, march, "/one", `["one"]`},
+			{"0123456789", "en", "page", "", later, later, "/two", `["two"]`},
+			{"9012345678", "en", "blog", "", march, march, "/three", `["three"]`},
+			{"8901234567", "en", "page", "", april, april, "/four", `["four"]`},
+		} {
+			_, err = tx.TX().Insert("page", values...)
+			SoMsg(fmt.Sprintf("insert #%d error", idx), err, ShouldBeNil)
+		}
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err := eql.Perform(`LOOKUP COUNT MONTH(.Created) AS period, .Type GROUP BY period, .Type ORDER BY period, .Type`)
+		SoMsg("monthly counts error", err, ShouldBeNil)
+		SoMsg("monthly counts", results, ShouldEqual, clContext.Contexts{
+			{"period": "2024-03", "type": "blog", "count": int64(1)},
+			{"period": "2024-03", "type": "page", "count": int64(2)},
+			{"period": "2024-04", "type": "page", "count": int64(1)},
+		})
+
+		_, results, err = eql.Perform(`LOOKUP DISTINCT DAY(.Updated) WITHIN YEAR(.Updated) == "2024" ORDER BY DAY(.Updated)`)
+		SoMsg("daily lookup error", err, ShouldBeNil)
+		SoMsg("daily lookup", results, ShouldEqual, clContext.Contexts{
+			{"updated": "2024-03-17"},
+			{"updated": "2024-03-30"},
+			{"updated": "2024-04-01"},
+		})
+
+		facets, err := eql.PerformFacets(`FACETS YEAR(.Created) AS published`)
+		SoMsg("yearly facets error", err, ShouldBeNil)
+		SoMsg("yearly facets", facets, ShouldEqual, []*Facet{
+			{Key: "published", Values: []*FacetValue{{Value: "2024", Count: 4}}},
+		})
+
+		for _, test := range []struct {
+			dialect  sqlbuilder.Dialect
+			expected string
+		}{
+			{dialects.Postgresql{}, `SELECT date_trunc('month', "be_eql_page"."created") AS "created", COUNT("be_eql_page"."id") AS "count" FROM "be_eql_page" GROUP BY date_trunc('month', "be_eql_page"."created");`},
+			{dialects.MySql{}, "SELECT DATE_FORMAT(`be_eql_page`.`created`, '%Y-%m') AS `created`, COUNT(`be_eql_page`.`id`) AS `count` FROM `be_eql_page` GROUP BY DATE_FORMAT(`be_eql_page`.`created`, '%Y-%m');"},
+		} {
+			other, err := New(makeBeConfig(), tdb.DBH(), test.dialect, SkipCreateTable, SkipCreateIndex)
+			SoMsg(test.dialect.Name()+" enjinql error", err, ShouldBeNil)
+			query, _, err := other.ToSQL(`LOOKUP COUNT MONTH(.Created) GROUP BY MONTH(.Created)`)
+			SoMsg(test.dialect.Name()+" bucket error", err, ShouldBeNil)
+			SoMsg(test.dialect.Name()+" bucket query", query, ShouldEqual, test.expected)
+		}
+
+	})
+
+	Convey("keyword source keys", t, func() {
+
+		config, err := NewConfig("kw_eql").
+			AddSource(PageSourceConfig()).
+			NewSource("event").
+			SetParent(PageSource).
+			NewIntValue("year").
+			NewStringValue("group", 32).
+			AddValue(NewTimeValue("day")).
+			DoneSource().
+			Make()
+		SoMsg("config error", err, ShouldBeNil)
+		eql, tdb := makeTestEQL(config)
+		defer tdb.Close()
+
+		march, _ := time.Parse("2006-01-02 15:04", "2024-03-17 11:25")
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		pid, err := tx.Insert("page", "1234567890", "en", "page", "", march, march, "/one", `["one"]`)
+		SoMsg("insert page error", err, ShouldBeNil)
+		_, err = tx.Insert("event", pid, 2024, "meetup", march)
+		SoMsg("insert event error", err, ShouldBeNil)
+		_, _, err = tx.Execute(`UPDATE event SET .group = "party" WITHIN event.year == 2024`)
+		SoMsg("update keyword key error", err, ShouldBeNil)
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err := eql.Perform(`LOOKUP event.year, event.group, DAY(event.day) AS happened WITHIN event.group == "party" ORDER BY event.year`)
+		SoMsg("keyword keys error", err, ShouldBeNil)
+		SoMsg("keyword keys", results, ShouldEqual, clContext.Contexts{
+			{"year": int64(2024), "group": "party", "happened": "2024-03-17"},
+		})
+
+	})
+}
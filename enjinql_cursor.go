@@ -0,0 +1,229 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+)
+
+const (
+	// CursorKeyPrefix is the column alias prefix used for the ORDER BY and
+	// id values selected by statements with an AFTER clause, PerformPage
+	// removes these columns from the results it returns
+	CursorKeyPrefix = "eql_cursor_"
+)
+
+// cCursorValue is the JSON representation of one cursor key value
+type cCursorValue struct {
+	T string `json:"t"`
+	V string `json:"v,omitempty"`
+}
+
+// encodeCursor returns the opaque continuation token for the given list of
+// ORDER BY and id values
+func encodeCursor(list []interface{}) (cursor string, err error) {
+	var encoded []cCursorValue
+	for _, value := range list {
+		var cv cCursorValue
+		switch t := value.(type) {
+		case nil:
+			cv.T = "n"
+		case int:
+			cv.T, cv.V = "i", strconv.FormatInt(int64(t), 10)
+		case int32:
+			cv.T, cv.V = "i", strconv.FormatInt(int64(t), 10)
+		case int64:
+			cv.T, cv.V = "i", strconv.FormatInt(t, 10)
+		case float32:
+			cv.T, cv.V = "f", strconv.FormatFloat(float64(t), 'g', -1, 64)
+		case float64:
+			cv.T, cv.V = "f", strconv.FormatFloat(t, 'g', -1, 64)
+		case bool:
+			cv.T, cv.V = "b", strconv.FormatBool(t)
+		case string:
+			cv.T, cv.V = "s", t
+		case []byte:
+			cv.T, cv.V = "x", base64.StdEncoding.EncodeToString(t)
+		case time.Time:
+			cv.T, cv.V = "t", t.Format(time.RFC3339Nano)
+		default:
+			err = fmt.Errorf("%w: unsupported value type %T", ErrInvalidCursor, t)
+			return
+		}
+		encoded = append(encoded, cv)
+	}
+	var data []byte
+	if data, err = json.Marshal(encoded); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+		return
+	}
+	cursor = base64.RawURLEncoding.EncodeToString(data)
+	return
+}
+
+// decodeCursor returns the list of values encoded within the given opaque
+// continuation token
+func decodeCursor(cursor string) (list []interface{}, err error) {
+	var data []byte
+	var encoded []cCursorValue
+	if data, err = base64.RawURLEncoding.DecodeString(cursor); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+		return
+	} else if err = json.Unmarshal(data, &encoded); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+		return
+	}
+	for _, cv := range encoded {
+		var value interface{}
+		switch cv.T {
+		case "n":
+		case "i":
+			value, err = strconv.ParseInt(cv.V, 10, 64)
+		case "f":
+			value, err = strconv.ParseFloat(cv.V, 64)
+		case "b":
+			value, err = strconv.ParseBool(cv.V)
+		case "s":
+			value = cv.V
+		case "x":
+			value, err = base64.StdEncoding.DecodeString(cv.V)
+		case "t":
+			value, err = time.Parse(time.RFC3339Nano, cv.V)
+		default:
+			err = fmt.Errorf("unknown value type %q", cv.T)
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+			return
+		}
+		list = append(list, value)
+	}
+	return
+}
+
+// isCursorKey returns true if the given column name is one of the cursor
+// values selected by statements with an AFTER clause
+func isCursorKey(name string) bool {
+	return strings.HasPrefix(name, CursorKeyPrefix)
+}
+
+// makeCursorKey returns the column alias for the cursor value at idx
+func makeCursorKey(idx int) string {
+	return CursorKeyPrefix + strconv.Itoa(idx)
+}
+
+// getAfterCursor returns the unquoted AFTER clause text, an empty cursor is
+// the first page of results
+func (p *cProcessor) getAfterCursor() (cursor string, err error) {
+	if p.syntax.After == nil {
+		return
+	} else if p.syntax.After.Placeholder != nil {
+		err = newSyntaxError(p.syntax.After.Pos, ErrInvalidSyntax, ErrAfterValue)
+		return
+	}
+	var other interface{}
+	if other, err = p.syntax.After.makeOther(p); err != nil {
+		return
+	}
+	cursor, _ = other.(string)
+	return
+}
+
+// prepareCursorColumns returns the ORDER BY columns followed by the id of the
+// first key source, which is the final tie-breaker for keyset pagination
+func (p *cProcessor) prepareCursorColumns() (columns []sqlbuilder.Column, err error) {
+	if ob := p.syntax.OrderBy; ob != nil {
+		if columns, err = ob.makeColumns(p); err != nil {
+			return
+		}
+	}
+	if sources := p.getKeySources(); len(sources) > 0 {
+		var t sqlbuilder.Table
+		if t, err = sources[0].getTable(); err != nil {
+			return
+		}
+		columns = append(columns, t.C(SourceIdKey))
+	} else {
+		err = fmt.Errorf("%w: AFTER requires at least one source key", ErrInvalidSyntax)
+	}
+	return
+}
+
+// makeKeysetCondition returns the condition selecting all rows which sort
+// after the given list of values, the list must be the same length as the
+// columns. NULL values are ordered as the dialect does: first for ascending
+// order except on PostgreSQL, which orders NULL values last
+func (p *cProcessor) makeKeysetCondition(desc bool, columns []sqlbuilder.Column, list []interface{}) (cond sqlbuilder.Condition, err error) {
+	if len(columns) != len(list) {
+		err = fmt.Errorf("%w: expected %d values, received %d", ErrInvalidCursor, len(columns), len(list))
+		return
+	}
+
+	nullsFirst := p.builder.Dialect().Name() != "postgresql"
+	// nullsAfter is true when NULL values sort after all others
+	nullsAfter := nullsFirst == desc
+
+	var branches []sqlbuilder.Condition
+	for idx, column := range columns {
+		var conditions []sqlbuilder.Condition
+		for jdx := 0; jdx < idx; jdx++ {
+			conditions = append(conditions, columns[jdx].Eq(list[jdx]))
+		}
+
+		var next sqlbuilder.Condition
+		if value := list[idx]; value == nil {
+			if nullsAfter {
+				// nothing sorts after NULL other than more NULL values,
+				// which are resolved by the following keys
+				continue
+			}
+			next = column.NotEq(nil)
+		} else {
+			if desc {
+				next = column.Lt(value)
+			} else {
+				next = column.Gt(value)
+			}
+			if nullsAfter {
+				next = sqlbuilder.Or(next, column.Eq(nil))
+			}
+		}
+
+		conditions = append(conditions, next)
+		if len(conditions) == 1 {
+			branches = append(branches, conditions[0])
+		} else {
+			branches = append(branches, sqlbuilder.And(conditions...))
+		}
+	}
+
+	switch len(branches) {
+	case 0:
+		// only possible when the cursor id value is NULL
+		err = fmt.Errorf("%w: cursor has no following rows", ErrInvalidCursor)
+	case 1:
+		cond = branches[0]
+	default:
+		cond = sqlbuilder.Or(branches...)
+	}
+	return
+}
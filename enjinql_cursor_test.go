@@ -0,0 +1,68 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+)
+
+func TestPerformPage(t *testing.T) {
+	Convey("keyset pagination", t, func() {
+
+		eql, _ := makeQfEQL()
+		defer eql.Close()
+
+		columns, results, next, err := eql.PerformPage("", `LOOKUP .Shasum ORDER BY .Shasum LIMIT 1`)
+		SoMsg("first page error", err, ShouldBeNil)
+		SoMsg("first page columns", columns, ShouldEqual, []string{"shasum"})
+		SoMsg("first page results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "0102000405"},
+		})
+		SoMsg("first page next", next, ShouldNotEqual, "")
+
+		columns, results, next, err = eql.PerformPage(next, `LOOKUP .Shasum ORDER BY .Shasum LIMIT 1`)
+		SoMsg("last page error", err, ShouldBeNil)
+		SoMsg("last page columns", columns, ShouldEqual, []string{"shasum"})
+		SoMsg("last page results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "1122334455"},
+		})
+		SoMsg("last page next", next, ShouldEqual, "")
+
+		_, results, next, err = eql.PerformPage("", `LOOKUP .Shasum WITHIN word.Word ^= "th" ORDER BY .Shasum DESC LIMIT 1`)
+		SoMsg("joined first page error", err, ShouldBeNil)
+		SoMsg("joined first page results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "1122334455"},
+		})
+		SoMsg("joined first page next", next, ShouldNotEqual, "")
+
+		_, results, next, err = eql.PerformPage(next, `LOOKUP .Shasum WITHIN word.Word ^= "th" ORDER BY .Shasum DESC LIMIT 1`)
+		SoMsg("joined last page error", err, ShouldBeNil)
+		SoMsg("joined last page results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "0102000405"},
+		})
+		SoMsg("joined last page next", next, ShouldEqual, "")
+
+		_, _, _, err = eql.PerformPage("not-a-cursor", `LOOKUP .Shasum LIMIT 1`)
+		SoMsg("invalid cursor error", err, ShouldWrap, ErrInvalidCursor)
+
+		_, _, _, err = eql.PerformPage("W10", `LOOKUP .Shasum LIMIT 1`)
+		SoMsg("short cursor error", err, ShouldWrap, ErrInvalidCursor)
+
+	})
+}
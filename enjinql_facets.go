@@ -0,0 +1,268 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+	"github.com/go-corelibs/values"
+)
+
+const (
+	// FacetValueKey is the column alias for the values of a facet query
+	FacetValueKey = "value"
+	// FacetCountKey is the column alias for the counts of a facet query
+	FacetCountKey = "count"
+)
+
+// FacetValue is one value of a Facet histogram
+type FacetValue struct {
+	// Value is the source key value
+	Value interface{} `json:"value"`
+	// Count is the number of distinct primary source rows with this Value
+	Count int64 `json:"count"`
+}
+
+// Facet is the histogram of values for one FACETS source key
+type Facet struct {
+	// Key is the source key alias, or the source key as written
+	Key string `json:"key"`
+	// Values are ordered from the highest count to the lowest and are no
+	// more than the FACETS LIMIT, when one is given
+	Values []*FacetValue `json:"values"`
+	// Other is the sum of the counts for all values beyond the FACETS LIMIT
+	Other int64 `json:"other,omitempty"`
+}
+
+func (eql *enjinql) Facets(within string, keys []string, argv ...interface{}) (facets []*Facet, err error) {
+	return eql.PerformFacets(makeFacetsFormat(within, keys), argv...)
+}
+
+func (eql *enjinql) PerformFacets(format string, argv ...interface{}) (facets []*Facet, err error) {
+	return eql.performFacets(eql.withReadTX, format, argv...)
+}
+
+// makeFacetsFormat returns the FACETS statement for the source keys and the
+// optional WITHIN expression given
+func makeFacetsFormat(within string, keys []string) (format string) {
+	format = "FACETS " + strings.Join(keys, ", ")
+	if within = strings.TrimSpace(within); within != "" {
+		format += " WITHIN " + within
+	}
+	return
+}
+
+// performFacets is PerformFacets with the histogram queries performed by the
+// run func given
+func (eql *enjinql) performFacets(run func(fn func(db SqlDB) (err error)) (err error), format string, argv ...interface{}) (facets []*Facet, err error) {
+	if err = eql.Ready(); err != nil {
+		return
+	}
+
+	var parsed *Syntax
+	if parsed, err = eql.Parse(format, argv...); err != nil {
+		return
+	} else if !parsed.Facets {
+		err = newSyntaxError(parsed.Pos, ErrInvalidSyntax, ErrMismatchFacets)
+		return
+	} else if err = parsed.Validate(); err != nil {
+		return
+	}
+
+	var prepared []*cFacetSQL
+	for _, sk := range parsed.Keys {
+		var facetSQL *cFacetSQL
+		if facetSQL, err = eql.prepareFacetSQL(parsed, sk); err != nil {
+			return
+		}
+		prepared = append(prepared, facetSQL)
+	}
+
+	err = run(func(db SqlDB) (err error) {
+		for idx, sk := range parsed.Keys {
+			facet := &Facet{Key: sk.String()}
+			if sk.Alias != nil {
+				facet.Key = *sk.Alias
+			}
+
+			var listed int64
+			if prepared[idx].query != "" {
+				var rows *sql.Rows
+				if rows, err = db.Query(prepared[idx].query, prepared[idx].argv...); err != nil {
+					return
+				}
+				_, results := scanContexts(rows)
+
+				for _, row := range results {
+					var count int64
+					if count, err = toFacetCount(row[FacetCountKey]); err != nil {
+						return
+					}
+					listed += count
+					facet.Values = append(facet.Values, &FacetValue{
+						Value: row[FacetValueKey],
+						Count: count,
+					})
+				}
+			}
+
+			// the values beyond the LIMIT are only summed when the LIMIT is
+			// reached
+			if prepared[idx].total != "" && len(facet.Values) >= *parsed.Limit {
+				var total interface{}
+				if err = db.QueryRow(prepared[idx].total, prepared[idx].totalArgv...).Scan(&total); err != nil {
+					return
+				}
+				var sum int64
+				if sum, err = toFacetCount(total); err != nil {
+					return
+				}
+				facet.Other = sum - listed
+			}
+
+			facets = append(facets, facet)
+		}
+		return
+	})
+	return
+}
+
+// toFacetCount returns the integer count of the FACETS count value given, the
+// drivers return integers, floats or numeric text depending on the dialect
+func toFacetCount(value interface{}) (count int64, err error) {
+	switch v := value.(type) {
+	case nil:
+		// the SUM of no rows is NULL
+	case int64:
+		count = v
+	case float64:
+		if v != math.Trunc(v) {
+			err = fmt.Errorf("%w: %v", ErrFacetCount, v)
+		} else {
+			count = int64(v)
+		}
+	case []byte:
+		if count, err = strconv.ParseInt(string(v), 10, 64); err != nil {
+			err = fmt.Errorf("%w: %q", ErrFacetCount, v)
+		}
+	case string:
+		if count, err = strconv.ParseInt(v, 10, 64); err != nil {
+			err = fmt.Errorf("%w: %q", ErrFacetCount, v)
+		}
+	default:
+		err = fmt.Errorf("%w: %T", ErrFacetCount, v)
+	}
+	return
+}
+
+// cFacetSQL is the prepared histogram query of one FACETS key and, when there
+// is a LIMIT, the query summing the counts of all the key values
+type cFacetSQL struct {
+	query     string
+	argv      []interface{}
+	total     string
+	totalArgv []interface{}
+}
+
+// prepareFacetSQL prepares the histogram query for one key of the FACETS
+// syntax given, counting the distinct primary source rows per key value. With
+// a LIMIT, the histogram query is limited and the total query sums the counts
+// of all the key values for the Facet.Other bucket
+func (eql *enjinql) prepareFacetSQL(syntax *Syntax, sk *SourceKey) (prepared *cFacetSQL, err error) {
+
+	// include the primary source id so that the plan joins the primary source
+	// and the counts are of the primary source rows
+	primary := &SourceKey{
+		Source: values.Ref(eql.sources.getPrimarySourceName()),
+		Key:    SourceIdKey,
+		Pos:    syntax.Pos,
+	}
+
+	var state *cProcessor
+	if state, err = eql.prepareSyntaxBuild(&Syntax{
+		Lookup:     true,
+		Unfiltered: syntax.Unfiltered,
+		Keys:       []*SourceKey{sk, primary},
+		Within:     syntax.Within,
+		Pos:        syntax.Pos,
+	}); err != nil {
+		return
+	}
+
+	var top sqlbuilder.Table
+	if top, err = state.prepareBuild(); err != nil {
+		return
+	}
+
+	state.build = eql.builder.Select(top)
+
+	var ok bool
+	var id *cProcessSrcKey
+	var value sqlbuilder.Column
+	if value, _, ok = state.getKeyColumn(sk); !ok {
+		err = newSyntaxError(sk.Pos, ErrInvalidSyntax, ErrColumnNotFound)
+		return
+	} else if id, ok = state.updated[primary.String()]; !ok {
+		err = newSyntaxError(sk.Pos, ErrInvalidSyntax, ErrColumnNotFound)
+		return
+	}
+
+	count := sqlbuilder.Func("COUNT", sqlbuilder.Func("DISTINCT", id.c))
+	state.build.Columns(value.As(FacetValueKey), count.As(FacetCountKey))
+
+	if state.syntax.Within != nil {
+		var cond sqlbuilder.Condition
+		if cond, err = state.syntax.Within.make(state); err != nil {
+			return
+		}
+		state.build.Where(cond)
+	}
+
+	state.build.GroupBy(value)
+	state.build.OrderBy(true, count)
+	state.build.OrderBy(false, value)
+
+	var query string
+	var argv []interface{}
+	if query, argv, err = state.build.ToSql(); err != nil {
+		return
+	} else if query, err = expandMarkers(eql.dialect, query); err != nil {
+		return
+	} else if syntax.Limit == nil {
+		prepared = &cFacetSQL{query: query, argv: argv}
+		return
+	}
+
+	suffix := eql.dialect.QuerySuffix()
+	prepared = &cFacetSQL{
+		total: "SELECT SUM(" + eql.dialect.QuoteField(FacetCountKey) + ") FROM (" +
+			strings.TrimSuffix(query, suffix) + ") AS " + eql.dialect.QuoteField("eql_facets") + suffix,
+		totalArgv: argv,
+	}
+
+	// the builder omits a LIMIT of zero, which has no values to query
+	if *syntax.Limit > 0 {
+		state.build.Limit(*syntax.Limit)
+		if prepared.query, prepared.argv, err = state.build.ToSql(); err == nil {
+			prepared.query, err = expandMarkers(eql.dialect, prepared.query)
+		}
+	}
+	return
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFacets(t *testing.T) {
+	Convey("facets", t, func() {
+
+		eql, _ := makeQfEQL()
+		defer eql.Close()
+
+		facets, err := eql.PerformFacets(`FACETS .type, word.word AS word WITHIN word.word != {1} LIMIT 3`, "nope")
+		SoMsg("perform facets error", err, ShouldBeNil)
+		SoMsg("perform facets", facets, ShouldEqual, []*Facet{
+			{Key: ".type", Values: []*FacetValue{
+				{Value: "quote", Count: 2},
+			}},
+			{Key: "word", Values: []*FacetValue{
+				{Value: "is", Count: 2},
+				{Value: "of", Count: 2},
+				{Value: "quote", Count: 2},
+			}, Other: 9},
+		})
+
+		facets, err = eql.PerformFacets(`FACETS word.word AS word WITHIN word.word != {1} LIMIT 0`, "nope")
+		SoMsg("perform facets limit zero error", err, ShouldBeNil)
+		SoMsg("perform facets limit zero", facets, ShouldEqual, []*Facet{
+			{Key: "word", Other: 15},
+		})
+
+		facets, err = eql.PerformFacets(`FACETS .type LIMIT 3`)
+		SoMsg("perform facets under limit error", err, ShouldBeNil)
+		SoMsg("perform facets under limit", facets, ShouldEqual, []*Facet{
+			{Key: ".type", Values: []*FacetValue{
+				{Value: "quote", Count: 2},
+			}},
+		})
+
+		facets, err = eql.Facets(`.shasum == {1}`, []string{".language", "word.word"}, "1122334455")
+		SoMsg("facets error", err, ShouldBeNil)
+		SoMsg("facets length", len(facets), ShouldEqual, 2)
+		SoMsg("facets language", facets[0], ShouldEqual, &Facet{
+			Key:    ".language",
+			Values: []*FacetValue{{Value: "en", Count: 1}},
+		})
+		SoMsg("facets words", len(facets[1].Values), ShouldEqual, 7)
+
+		_, err = eql.PerformFacets(`LOOKUP .type`)
+		SoMsg("perform lookup error", err, ShouldNotBeNil)
+
+		_, _, err = eql.Perform(`FACETS .type`)
+		SoMsg("perform facets statement error", err, ShouldEqual, ErrFacetsStatement)
+
+		for idx, check := range []struct {
+			value interface{}
+			count int64
+			err   error
+		}{
+			{nil, 0, nil},
+			{int64(3), 3, nil},
+			{float64(4), 4, nil},
+			{[]byte("5"), 5, nil},
+			{"6", 6, nil},
+			{4.5, 0, ErrFacetCount},
+			{"nope", 0, ErrFacetCount},
+			{true, 0, ErrFacetCount},
+		} {
+			count, err := toFacetCount(check.value)
+			SoMsg(fmt.Sprintf("facet count #%d", idx), count, ShouldEqual, check.count)
+			if check.err == nil {
+				SoMsg(fmt.Sprintf("facet count #%d error", idx), err, ShouldBeNil)
+			} else {
+				SoMsg(fmt.Sprintf("facet count #%d error", idx), err, ShouldWrap, check.err)
+			}
+		}
+
+	})
+}
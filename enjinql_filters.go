@@ -0,0 +1,154 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/iancoleman/strcase"
+)
+
+var (
+	gFilterParser = participle.MustBuild[Expression](
+		participle.Lexer(gSyntaxLexer),
+		participle.CaseInsensitive("Keyword"),
+	)
+)
+
+type cFilter struct {
+	source     string
+	expression string
+}
+
+// WithFilter is an Option for adding a default filter to the named source,
+// in addition to any SourceConfig.Filters. The format and argv are prepared
+// with PrepareSyntax, for filtering on values known when the EnjinQL instance
+// is created, for example:
+//
+//	enjinql.WithFilter("page", ".language == {1}", "en")
+func WithFilter(source, format string, argv ...interface{}) Option {
+	return func(o *option) (err error) {
+		var expression string
+		if expression, err = PrepareSyntax(format, argv...); err != nil {
+			return
+		}
+		o.filters = append(o.filters, cFilter{source: source, expression: expression})
+		return
+	}
+}
+
+// parseFilter parses the filter expression given, with all source keys
+// lacking a source name qualified with the source given
+func parseFilter(source, expression string) (filter *Expression, err error) {
+	if filter, err = gFilterParser.ParseString("filter", expression); err != nil {
+		return
+	} else if err = filter.validate(); err != nil {
+		filter = nil
+		return
+	}
+	filter.qualify(source)
+	return
+}
+
+// initFilters parses the default filters of all sources, the SourceConfig
+// filters first and then those given with the WithFilter option
+func (eql *enjinql) initFilters() (err error) {
+	eql.filters = make(map[string][]*Expression)
+
+	add := func(source, expression string) (err error) {
+		var filter *Expression
+		if filter, err = parseFilter(source, expression); err != nil {
+			return fmt.Errorf("%w: %q - %w", ErrInvalidFilter, expression, err)
+		}
+		eql.filters[source] = append(eql.filters[source], filter)
+		return
+	}
+
+	for _, sc := range eql.config.Sources {
+		for _, expression := range sc.Filters {
+			if err = add(sc.Name, expression); err != nil {
+				return
+			}
+		}
+	}
+
+	for _, f := range eql.option.filters {
+		if !eql.sources.exists(f.source) {
+			return fmt.Errorf("%w: %w (%q)", ErrInvalidFilter, ErrSourceNotFound, f.source)
+		} else if err = add(f.source, f.expression); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// applyFilters returns a copy of the syntax given with the default filters of
+// all the sources used ANDed into the WITHIN expression, including those of
+// the sources used by the filters themselves. Statements flagged UNFILTERED,
+// and sources of other namespaces, are not filtered
+func (eql *enjinql) applyFilters(syntax *Syntax) (filtered *Syntax, err error) {
+	if syntax.Unfiltered || len(eql.filters) == 0 {
+		return syntax, nil
+	}
+
+	primary := eql.sources.getPrimarySourceName()
+	seen := make(map[string]struct{})
+	var expressions []*Expression
+
+	var visit func(found []*SrcKey)
+	visit = func(found []*SrcKey) {
+		for _, sk := range found {
+			if sk.Namespace != "" {
+				continue
+			}
+			name := strcase.ToSnake(sk.Src)
+			if name == "" {
+				name = primary
+			}
+			if _, present := seen[name]; present {
+				continue
+			}
+			seen[name] = struct{}{}
+			for _, filter := range eql.filters[name] {
+				expressions = append(expressions, filter)
+				visit(filter.findSources())
+			}
+		}
+	}
+	visit(syntax.findSources())
+
+	if len(expressions) == 0 {
+		return syntax, nil
+	}
+
+	within := syntax.Within
+	for _, expression := range expressions {
+		if within == nil {
+			within = expression
+			continue
+		}
+		within = &Expression{
+			Condition: &Condition{Left: within, Type: "AND", Right: expression, Pos: syntax.Pos},
+			Pos:       syntax.Pos,
+		}
+	}
+
+	clone := *syntax
+	clone.Within = within
+	filtered = &clone
+	return
+}
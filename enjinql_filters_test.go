@@ -0,0 +1,100 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+	"github.com/go-corelibs/go-sqlbuilder/dialects"
+)
+
+func TestDefaultFilters(t *testing.T) {
+	Convey("default filters", t, func() {
+
+		config, err := NewConfig("be_eql").
+			AddSource(PageSourceConfig().AddFilter(`.type != "draft"`)).
+			NewSource("page_flag").
+			SetParent(PageSource).
+			NewBoolValue("hidden").
+			DoneSource().
+			Make()
+		SoMsg("config error", err, ShouldBeNil)
+
+		eql, tdb := makeTestEQL(config, WithFilter(PageSource, ".language == {1}", "en"))
+		defer tdb.Close()
+
+		created, _ := time.Parse("2006-01-02 15:04", "2024-03-17 11:25")
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		for idx, values := range [][]interface{}{
+			{"1234567890", "en", "page", "", created, created, "/one", `["one"]`},
+			{"0123456789", "en", "draft", "", created, created, "/two", `["two"]`},
+			{"9012345678", "fr", "page", "", created, created, "/three", `["three"]`},
+			{"8901234567", "en", "blog", "", created, created, "/four", `["four"]`},
+		} {
+			_, err = tx.TX().Insert("page", values...)
+			SoMsg(fmt.Sprintf("insert #%d error", idx), err, ShouldBeNil)
+		}
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		query, _, err := eql.ToSQL(`LOOKUP .Url WITHIN .Shasum != "nope"`)
+		SoMsg("filtered sql error", err, ShouldBeNil)
+		SoMsg("filtered sql", query, ShouldEqual, `SELECT "be_eql_page"."url" FROM "be_eql_page" WHERE ( "be_eql_page"."shasum"<>? AND "be_eql_page"."type"<>? ) AND "be_eql_page"."language"=?;`)
+
+		_, results, err := eql.Perform(`LOOKUP .Url ORDER BY .Url`)
+		SoMsg("filtered lookup error", err, ShouldBeNil)
+		SoMsg("filtered lookup", results, ShouldEqual, clContext.Contexts{
+			{"url": "/four"},
+			{"url": "/one"},
+		})
+
+		_, results, err = eql.Perform(`LOOKUP UNFILTERED .Url ORDER BY .Url`)
+		SoMsg("unfiltered lookup error", err, ShouldBeNil)
+		SoMsg("unfiltered lookup", len(results), ShouldEqual, 4)
+
+		facets, err := eql.PerformFacets(`FACETS .type`)
+		SoMsg("filtered facets error", err, ShouldBeNil)
+		SoMsg("filtered facets", facets, ShouldEqual, []*Facet{
+			{Key: ".type", Values: []*FacetValue{{Value: "blog", Count: 1}, {Value: "page", Count: 1}}},
+		})
+
+		facets, err = eql.PerformFacets(`FACETS UNFILTERED .language`)
+		SoMsg("unfiltered facets error", err, ShouldBeNil)
+		SoMsg("unfiltered facets", facets, ShouldEqual, []*Facet{
+			{Key: ".language", Values: []*FacetValue{{Value: "en", Count: 3}, {Value: "fr", Count: 1}}},
+		})
+
+		joined, err := New(config, tdb.DBH(), dialects.Sqlite{}, SkipCreateTable, SkipCreateIndex,
+			WithFilter(PageSource, "page_flag.hidden == false"))
+		SoMsg("new joined enjinql error", err, ShouldBeNil)
+		query, _, err = joined.ToSQL(`LOOKUP .Url`)
+		SoMsg("joined filter sql error", err, ShouldBeNil)
+		SoMsg("joined filter sql", query, ShouldContainSubstring, `INNER JOIN "be_eql_page_flag"`)
+		query, _, err = joined.ToSQL(`LOOKUP UNFILTERED .Url`)
+		SoMsg("joined unfiltered sql error", err, ShouldBeNil)
+		SoMsg("joined unfiltered sql", query, ShouldEqual, `SELECT "be_eql_page"."url" FROM "be_eql_page";`)
+
+		_, err = New(config, tdb.DBH(), dialects.Sqlite{}, SkipCreateTable, SkipCreateIndex, WithFilter("nope", ".type == 'page'"))
+		SoMsg("unknown filter source error", err, ShouldWrap, ErrSourceNotFound)
+		_, err = New(config, tdb.DBH(), dialects.Sqlite{}, SkipCreateTable, SkipCreateIndex, WithFilter(PageSource, ".type =="))
+		SoMsg("invalid filter error", err, ShouldWrap, ErrInvalidFilter)
+
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const (
+	// gGarbageBatchSize is the maximum number of orphaned rows deleted by
+	// each CollectGarbage transaction
+	gGarbageBatchSize = 500
+)
+
+type cGarbageTarget struct {
+	source *cSource
+	table  string
+	where  string
+}
+
+// getGarbageTargets returns the data sources to collect garbage from, which
+// is all data sources with linked values referencing them when no names are
+// given
+func (eql *enjinql) getGarbageTargets(names ...string) (targets []*cGarbageTarget, err error) {
+	if len(names) == 0 {
+		names = eql.sources.graph.linked()
+	}
+
+	for _, name := range names {
+		source, ok := eql.sources.getSource(name)
+		if !ok {
+			err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+			return
+		}
+		linkers, ok := eql.sources.graph.garbageLinkers(source.name)
+		if !ok {
+			err = fmt.Errorf("%w: %q", ErrGarbageSource, name)
+			return
+		}
+
+		table := eql.dialect.QuoteField(source.formal())
+		var conditions []string
+		for _, link := range linkers {
+			linker, _ := eql.sources.getSource(link.this.table)
+			other := eql.dialect.QuoteField(linker.formal())
+			conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM "+other+
+				" WHERE "+other+"."+eql.dialect.QuoteField(link.this.key)+
+				" = "+table+"."+eql.dialect.QuoteField(link.other.key)+")")
+		}
+		targets = append(targets, &cGarbageTarget{
+			source: source,
+			table:  table,
+			where:  strings.Join(conditions, " AND "),
+		})
+	}
+	return
+}
+
+func (eql *enjinql) CollectGarbage(ctx context.Context, dryRun bool, sources ...string) (collected map[string]int64, err error) {
+	if err = eql.Ready(); err != nil {
+		return
+	}
+
+	var targets []*cGarbageTarget
+	eql.m.RLock()
+	targets, err = eql.getGarbageTargets(sources...)
+	eql.m.RUnlock()
+	if err != nil {
+		return
+	}
+
+	collected = make(map[string]int64)
+	for _, target := range targets {
+		var count int64
+		if dryRun {
+			query := "SELECT COUNT(*) FROM " + target.table + " WHERE " + target.where + eql.dialect.QuerySuffix()
+			if err = eql.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+				return
+			}
+		} else if count, err = eql.collectGarbage(ctx, target); err != nil {
+			return
+		}
+		collected[target.source.name] = count
+	}
+	return
+}
+
+// collectGarbage deletes the orphaned rows of the target given, in batches of
+// gGarbageBatchSize rows, along with any rows depending on them. Each batch
+// is selected and deleted within one transaction and the deletions re-check
+// that the rows are still orphaned
+func (eql *enjinql) collectGarbage(ctx context.Context, target *cGarbageTarget) (collected int64, err error) {
+	id := target.table + "." + eql.dialect.QuoteField(SourceIdKey)
+	query := "SELECT " + id + " FROM " + target.table + " WHERE " + target.where +
+		" ORDER BY " + id + " LIMIT " + fmt.Sprint(gGarbageBatchSize) + eql.dialect.QuerySuffix()
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		var count int
+		var deleted int64
+		if count, deleted, err = eql.collectGarbageBatch(ctx, target, id, query); err != nil {
+			return
+		}
+
+		if collected += deleted; count < gGarbageBatchSize {
+			return
+		}
+	}
+}
+
+// collectGarbageBatch deletes the next batch of orphaned rows, returning the
+// number of rows selected and the number of rows deleted
+func (eql *enjinql) collectGarbageBatch(ctx context.Context, target *cGarbageTarget, id, query string) (count int, deleted int64, err error) {
+	var tx *sql.Tx
+	if tx, err = eql.db.db.BeginTx(ctx, nil); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var ids []interface{}
+	if ids, err = queryGarbageIDs(ctx, tx, query); err != nil {
+		return
+	} else if count = len(ids); count == 0 {
+		err = tx.Commit()
+		return
+	}
+
+	var binds []string
+	for idx := range ids {
+		binds = append(binds, eql.dialect.BindVar(idx+1))
+	}
+	where := id + " IN (" + strings.Join(binds, ", ") + ") AND " + target.where
+
+	var statements []string
+	eql.m.RLock()
+	statements, err = eql.prepareCascadeSQL(target.source.name, where, make(map[string]struct{}))
+	eql.m.RUnlock()
+	if err != nil {
+		return
+	}
+
+	// the target rows are deleted by the last statement
+	for _, statement := range statements {
+		var result sql.Result
+		if result, err = tx.ExecContext(ctx, statement, ids...); err != nil {
+			err = fmt.Errorf("%w: %w (%q)", ErrDeleteRows, err, statement)
+			return
+		} else if deleted, err = result.RowsAffected(); err != nil {
+			err = fmt.Errorf("%w: %w", ErrDeleteRows, err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	return
+}
+
+func queryGarbageIDs(ctx context.Context, tx *sql.Tx, query string) (ids []interface{}, err error) {
+	var rows *sql.Rows
+	if rows, err = tx.QueryContext(ctx, query); err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	return
+}
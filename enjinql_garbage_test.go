@@ -0,0 +1,80 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	sqlContext "context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	Convey("CollectGarbage", t, func() {
+
+		eql, dbh := makeQfEQL()
+		defer dbh.Close()
+
+		collected, err := eql.CollectGarbage(sqlContext.Background(), true)
+		SoMsg("dry-run error", err, ShouldBeNil)
+		SoMsg("dry-run nothing", collected, ShouldEqual, map[string]int64{"word": 0})
+
+		_, err = eql.CollectGarbage(sqlContext.Background(), true, PageSource)
+		SoMsg("primary source", err, ShouldWrap, ErrGarbageSource)
+		_, err = eql.CollectGarbage(sqlContext.Background(), true, "page_words")
+		SoMsg("link source", err, ShouldWrap, ErrGarbageSource)
+		_, err = eql.CollectGarbage(sqlContext.Background(), true, "nope")
+		SoMsg("source not found", err, ShouldWrap, ErrSourceNotFound)
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		_, err = tx.DeleteCascade(PageSource, 1)
+		SoMsg("delete cascade error", err, ShouldBeNil)
+		// an unreferenced word with a child row
+		wordId, err := tx.Insert("word", "o", "orphan", "orphan")
+		SoMsg("insert word error", err, ShouldBeNil)
+		_, err = tx.Insert("word_letters", wordId, "o")
+		SoMsg("insert word letter error", err, ShouldBeNil)
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, words, err := eql.SqlQuery(`SELECT COUNT(*) AS total FROM qf_eql_word`)
+		SoMsg("words query error", err, ShouldBeNil)
+
+		collected, err = eql.CollectGarbage(sqlContext.Background(), true, "word")
+		SoMsg("dry-run orphans error", err, ShouldBeNil)
+		SoMsg("dry-run orphans", collected, ShouldEqual, map[string]int64{"word": 8})
+
+		ctx, cancel := sqlContext.WithCancel(sqlContext.Background())
+		cancel()
+		_, err = eql.CollectGarbage(ctx, false)
+		SoMsg("cancelled", err, ShouldWrap, sqlContext.Canceled)
+
+		collected, err = eql.CollectGarbage(sqlContext.Background(), false)
+		SoMsg("collect error", err, ShouldBeNil)
+		SoMsg("collected orphans", collected, ShouldEqual, map[string]int64{"word": 8})
+
+		_, results, err := eql.SqlQuery(`SELECT COUNT(*) AS total FROM qf_eql_word`)
+		SoMsg("words after error", err, ShouldBeNil)
+		SoMsg("words after", results[0]["total"], ShouldEqual, words[0]["total"].(int64)-8)
+		_, results, err = eql.SqlQuery(`SELECT COUNT(*) AS total FROM qf_eql_word_letters`)
+		SoMsg("letters after error", err, ShouldBeNil)
+		SoMsg("letters after", results[0]["total"], ShouldEqual, 0)
+
+		collected, err = eql.CollectGarbage(sqlContext.Background(), true)
+		SoMsg("dry-run after error", err, ShouldBeNil)
+		SoMsg("dry-run after", collected, ShouldEqual, map[string]int64{"word": 0})
+
+	})
+}
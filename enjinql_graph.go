@@ -124,6 +124,78 @@ func (g *gSourcePlan) add(join *gSourceJoin) {
 	}
 }
 
+// fansOut reports whether the INNER JOIN statements of this plan can repeat
+// the rows of the given key sources. Starting from the first key source, any
+// branch of the join tree which does not lead to another key source will
+// multiply the results if any step along that branch is a one-to-many
+// relationship (the next table holds the foreign key, not the "id")
+func (g *gSourcePlan) fansOut(keys ...string) (fanned bool) {
+	if len(g.joins) == 0 || len(keys) == 0 {
+		return
+	}
+
+	type gSourceStep struct {
+		next string
+		many bool
+	}
+
+	steps := make(map[string][]gSourceStep)
+	for _, join := range g.joins {
+		steps[join.other.table] = append(steps[join.other.table], gSourceStep{
+			next: join.this.table,
+			many: join.this.key != SourceIdKey,
+		})
+		steps[join.this.table] = append(steps[join.this.table], gSourceStep{
+			next: join.other.table,
+			many: join.other.key != SourceIdKey,
+		})
+	}
+
+	lookup := slices.MakeLookup(keys)
+
+	var hasKeys func(name, from string) bool
+	hasKeys = func(name, from string) bool {
+		if _, present := lookup[name]; present {
+			return true
+		}
+		for _, step := range steps[name] {
+			if step.next != from && hasKeys(step.next, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var hasMany func(name, from string) bool
+	hasMany = func(name, from string) bool {
+		for _, step := range steps[name] {
+			if step.next != from && (step.many || hasMany(step.next, name)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var walk func(name, from string) bool
+	walk = func(name, from string) bool {
+		for _, step := range steps[name] {
+			if step.next == from {
+				continue
+			} else if hasKeys(step.next, name) {
+				if walk(step.next, name) {
+					return true
+				}
+			} else if step.many || hasMany(step.next, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	fanned = walk(keys[0], "")
+	return
+}
+
 // gSourceNode represents a single source instance and tracks a mapping of
 // other linked sources
 type gSourceNode struct {
@@ -134,6 +206,9 @@ type gSourceNode struct {
 	parent *gSourceJoin
 	// link is a mapping of other source names to specific table joins
 	link map[string]*gSourceJoin
+	// refs are the joins of linked values to the primary source, which are
+	// not planned as links
+	refs []*gSourceJoin
 }
 
 func newSourceNodeData(name string) *gSourceNode {
@@ -198,8 +273,9 @@ func (g *gSourceGraph) Add(nodes ...*gSourceNode) (err error) {
 
 	for _, node := range nodes {
 		if _, present := g.lookup[node.name]; !present {
-			if len(g.nodes) == 0 {
-				// the first added is considered the primary source
+			if g.primary == "" {
+				// without a designated primary source, the first added is
+				// considered the primary source
 				g.primary = node.name
 			}
 			g.lookup[node.name] = node
@@ -262,6 +338,70 @@ func (g *gSourceGraph) getNode(name string) (found *gSourceNode) {
 	return
 }
 
+// references returns the joins of all parent and linked values, with this
+// being the referencing source column and other being the referenced source
+// column
+func (g *gSourceGraph) references() (refs []*gSourceJoin) {
+	g.m.RLock()
+	defer g.m.RUnlock()
+	for _, node := range g.nodes {
+		if node.parent != nil {
+			refs = append(refs, node.parent)
+		}
+		for _, name := range maps.SortedKeys(node.link) {
+			// link joins are from the linked source to this one
+			link := node.link[name]
+			refs = append(refs, &gSourceJoin{table: node.name, this: link.other, other: link.this})
+		}
+		refs = append(refs, node.refs...)
+	}
+	return
+}
+
+// linkers returns the joins, like references, of the linked values which
+// reference the named source
+func (g *gSourceGraph) linkers(name string) (joins []*gSourceJoin) {
+	g.m.RLock()
+	defer g.m.RUnlock()
+	return g.linkersUnsafe(name)
+}
+
+func (g *gSourceGraph) linkersUnsafe(name string) (joins []*gSourceJoin) {
+	for _, node := range g.nodes {
+		for _, key := range maps.SortedKeys(node.link) {
+			if link := node.link[key]; link.this.table == name {
+				joins = append(joins, &gSourceJoin{table: node.name, this: link.other, other: link.this})
+			}
+		}
+	}
+	return
+}
+
+// linked returns the names of the data sources, other than the primary
+// source, which are referenced by linked values
+func (g *gSourceGraph) linked() (names []string) {
+	g.m.RLock()
+	defer g.m.RUnlock()
+	for _, node := range g.nodes {
+		if node.name != g.primary && node.isData() && len(g.linkersUnsafe(node.name)) > 0 {
+			names = append(names, node.name)
+		}
+	}
+	return
+}
+
+// garbageLinkers returns the linkers of the named source when it is a data
+// source, other than the primary source, referenced by linked values
+func (g *gSourceGraph) garbageLinkers(name string) (joins []*gSourceJoin, ok bool) {
+	g.m.RLock()
+	defer g.m.RUnlock()
+	if node, present := g.lookup[name]; present && node.isData() && name != g.primary {
+		joins = g.linkersUnsafe(name)
+		ok = len(joins) > 0
+	}
+	return
+}
+
 func (g *gSourceGraph) validate() (err error) {
 	g.m.RLock()
 	defer g.m.RUnlock()
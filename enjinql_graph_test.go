@@ -306,5 +306,24 @@ func TestSourceGraph(t *testing.T) {
 			SoMsg("plan "+test.label+": yes", plan.String(), ShouldEqual, test.plan.String())
 		}
 
+		for _, test := range []struct {
+			label   string
+			sources []string
+			keys    []string
+			fanned  bool
+		}{
+			{"only one link", []string{"title"}, []string{"title"}, false},
+			{"page with word", []string{"page", "word"}, []string{"page"}, true},
+			{"page and word", []string{"page", "word"}, []string{"page", "word"}, false},
+			{"word with page", []string{"page", "word"}, []string{"word"}, true},
+			{"word with letters", []string{"word", "word_letters"}, []string{"word"}, true},
+			{"letters with word", []string{"word", "word_letters"}, []string{"word_letters"}, false},
+			{"title with redirect", []string{"title", "redirect"}, []string{"title"}, true},
+		} {
+			plan, err := sg.plan(test.sources...)
+			SoMsg("fansOut "+test.label+": err", err, ShouldBeNil)
+			SoMsg("fansOut "+test.label+": yes", plan.fansOut(test.keys...), ShouldEqual, test.fanned)
+		}
+
 	})
 }
@@ -0,0 +1,89 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+)
+
+const (
+	// gJsonMarker prefixes the placeholder function names used for JSON path
+	// extraction, see expandJsonPaths
+	gJsonMarker = "EQL_JSON_"
+)
+
+var (
+	// gJsonFormats are the dialect specific JSON path SQL expressions
+	gJsonFormats = map[string]func(column string, path *JsonPath) string{
+		"sqlite3": func(column string, path *JsonPath) string {
+			return "json_extract(" + column + ", '" + path.path() + "')"
+		},
+		"postgresql": func(column string, path *JsonPath) string {
+			expr := "(" + column + "::jsonb"
+			steps := path.steps()
+			for idx, step := range steps {
+				op := "->"
+				if idx == len(steps)-1 {
+					op = "->>"
+				}
+				switch t := step.(type) {
+				case string:
+					expr += op + "'" + t + "'"
+				case int:
+					expr += op + strconv.Itoa(t)
+				}
+			}
+			return expr + ")"
+		},
+		"mysql": func(column string, path *JsonPath) string {
+			return "JSON_UNQUOTE(JSON_EXTRACT(" + column + ", '" + path.path() + "'))"
+		},
+	}
+
+	gJsonPattern = regexp.MustCompile(gJsonMarker + `([0-9a-f]+)\(([^()]*)\)`)
+)
+
+// makeJsonPath returns a placeholder function wrapping the given column, which
+// expandJsonPaths replaces with the dialect specific SQL. The JSON path is
+// hex encoded within the placeholder function name
+func makeJsonPath(path *JsonPath, column sqlbuilder.Column) sqlbuilder.Column {
+	return sqlbuilder.Func(gJsonMarker+hex.EncodeToString([]byte(path.path())), column)
+}
+
+// expandJsonPaths replaces all JSON path placeholders in the query with the
+// dialect specific SQL
+func expandJsonPaths(dialect sqlbuilder.Dialect, query string) (expanded string, err error) {
+	if !strings.Contains(query, gJsonMarker) {
+		return query, nil
+	}
+	format, ok := gJsonFormats[dialect.Name()]
+	if !ok {
+		err = fmt.Errorf("%w: %q", ErrJsonDialect, dialect.Name())
+		return
+	}
+	expanded = gJsonPattern.ReplaceAllStringFunc(query, func(match string) string {
+		m := gJsonPattern.FindStringSubmatch(match)
+		decoded, _ := hex.DecodeString(m[1])
+		quoted := strconv.Quote(string(decoded))
+		return format(m[2], &JsonPath{Quoted: &quoted})
+	})
+	return
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+	"github.com/go-corelibs/go-sqlbuilder"
+	"github.com/go-corelibs/go-sqlbuilder/dialects"
+)
+
+func TestJsonPaths(t *testing.T) {
+	Convey("json paths", t, func() {
+
+		config, _ := NewConfig("be_eql").
+			AddSource(PageSourceConfig()).
+			Make()
+		eql, tdb := makeTestEQL(config)
+		defer tdb.Close()
+
+		now, _ := time.Parse("2006-01-02 15:04", "2024-03-17 11:25")
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		for idx, values := range [][]interface{}{
+			{"1234567890", "en", "page", "", now, now, "/one", `{"title":"One","tags":["a","b"],"meta":{"rank":2}}`},
+			{"0123456789", "en", "page", "", now, now, "/two", `{"title":"Two","tags":["b"],"meta":{"rank":1}}`},
+			{"9012345678", "en", "page", "", now, now, "/three", `{"title":"Three","tags":[],"meta":{"rank":3}}`},
+		} {
+			_, err = tx.TX().Insert("page", values...)
+			SoMsg(fmt.Sprintf("insert #%d error", idx), err, ShouldBeNil)
+		}
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err := eql.Perform(`LOOKUP .Shasum, .Stub->"$.title" ORDER BY .Stub.meta.rank DESC`)
+		SoMsg("titles error", err, ShouldBeNil)
+		SoMsg("titles", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "9012345678", "title": "Three"},
+			{"shasum": "1234567890", "title": "One"},
+			{"shasum": "0123456789", "title": "Two"},
+		})
+
+		_, results, err = eql.Perform(`LOOKUP .Stub->'$.tags[0]' AS tag WITHIN .Stub.meta.rank <= {1} ORDER BY tag`, 2)
+		SoMsg("tags error", err, ShouldBeNil)
+		SoMsg("tags", results, ShouldEqual, clContext.Contexts{
+			{"tag": "a"},
+			{"tag": "b"},
+		})
+
+		_, _, err = eql.Perform(`LOOKUP .Created.title`)
+		SoMsg("non-string key error", err, ShouldWrap, ErrJsonPathKey)
+
+		_, err = ParseSyntax(`LOOKUP .Stub->"$.title; DROP"`)
+		SoMsg("invalid path error", err, ShouldNotBeNil)
+
+		for _, test := range []struct {
+			dialect  sqlbuilder.Dialect
+			expected string
+		}{
+			{dialects.Postgresql{}, `SELECT ("be_eql_page"."stub"::jsonb->'tags'->>0) AS "tag" FROM "be_eql_page" WHERE ("be_eql_page"."stub"::jsonb->'meta'->>'rank')=$1;`},
+			{dialects.MySql{}, "SELECT JSON_UNQUOTE(JSON_EXTRACT(`be_eql_page`.`stub`, '$.tags[0]')) AS `tag` FROM `be_eql_page` WHERE JSON_UNQUOTE(JSON_EXTRACT(`be_eql_page`.`stub`, '$.meta.rank'))=?;"},
+		} {
+			other, err := New(makeBeConfig(), tdb.DBH(), test.dialect, SkipCreateTable, SkipCreateIndex)
+			SoMsg(test.dialect.Name()+" enjinql error", err, ShouldBeNil)
+			query, _, err := other.ToSQL(`LOOKUP .Stub->"$.tags[0]" AS tag WITHIN .Stub.meta.rank == "2"`)
+			SoMsg(test.dialect.Name()+" json path error", err, ShouldBeNil)
+			SoMsg(test.dialect.Name()+" json path query", query, ShouldEqual, test.expected)
+		}
+
+	})
+}
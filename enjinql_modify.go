@@ -0,0 +1,311 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/iancoleman/strcase"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+	"github.com/go-corelibs/values"
+)
+
+const (
+	gSubqueryMarker = "EQL_SUBQUERY"
+	gSubqueryAlias  = "eql_ids"
+)
+
+var (
+	rxSubqueryMarker = regexp.MustCompile(gSubqueryMarker + `\([^)]*\)`)
+)
+
+// prepareModifySQL returns the SQL statement for the parsed Modify given
+func (eql *enjinql) prepareModifySQL(parsed *Modify) (query string, argv []interface{}, err error) {
+	if err = parsed.Validate(); err != nil {
+		return
+	}
+	switch {
+	case parsed.Insert != nil:
+		query, argv, err = eql.prepareInsertSQL(parsed)
+	case parsed.Update != nil:
+		sets := make([]*cModifySet, len(parsed.Update.Set))
+		for idx, set := range parsed.Update.Set {
+			sets[idx] = &cModifySet{key: set.Key}
+			if sets[idx].value, err = parsed.makeValue(set.Value); err != nil {
+				return
+			}
+		}
+		query, argv, err = eql.prepareWithinSQL(parsed.Update.Source, parsed.Update.Within, parsed.Update.Pos, sets)
+	case parsed.Delete != nil:
+		query, argv, err = eql.prepareWithinSQL(parsed.Delete.Source, parsed.Delete.Within, parsed.Delete.Pos, nil)
+	}
+	return
+}
+
+// cModifySet is one UPDATE source key value
+type cModifySet struct {
+	key   string
+	value interface{}
+}
+
+// getModifySource returns the named source and its table
+func (eql *enjinql) getModifySource(name string) (source *cSource, table sqlbuilder.Table, err error) {
+	var ok bool
+	if source, ok = eql.sources.getSource(strcase.ToSnake(name)); !ok {
+		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+		return
+	}
+	table, err = source.getTable()
+	return
+}
+
+// getModifyColumn returns the table column of the named source key
+func getModifyColumn(source *cSource, key string) (column sqlbuilder.Column, err error) {
+	var ok bool
+	if column, ok = source.keys[strcase.ToSnake(key)]; !ok || sqlbuilder.IsColumnError(column) {
+		err = fmt.Errorf("%w: %q", ErrColumnNotFound, key)
+	}
+	return
+}
+
+func (eql *enjinql) prepareInsertSQL(parsed *Modify) (query string, argv []interface{}, err error) {
+	var source *cSource
+	var table sqlbuilder.Table
+	if source, table, err = eql.getModifySource(parsed.Insert.Source); err != nil {
+		return
+	}
+
+	var columns []sqlbuilder.Column
+	var list []interface{}
+	for idx, key := range parsed.Insert.Keys {
+		var column sqlbuilder.Column
+		var value interface{}
+		if column, err = getModifyColumn(source, key); err != nil {
+			return
+		} else if value, err = parsed.makeValue(parsed.Insert.Values[idx]); err != nil {
+			return
+		}
+		columns = append(columns, column)
+		list = append(list, value)
+	}
+
+	query, argv, err = eql.builder.
+		Insert(table).
+		Columns(columns...).
+		Values(list...).
+		ToSql()
+	return
+}
+
+// prepareWithinSQL returns the UPDATE statement for the sets given, or the
+// DELETE statement when there are none. The WITHIN expression is planned as a LOOKUP of the source id key,
+// and when any other sources are joined, the rows modified are those with the
+// ids selected by a subquery of that LOOKUP
+func (eql *enjinql) prepareWithinSQL(name string, within *Expression, pos lexer.Position, sets []*cModifySet) (query string, argv []interface{}, err error) {
+	var source *cSource
+	var table sqlbuilder.Table
+	if source, table, err = eql.getModifySource(name); err != nil {
+		return
+	}
+
+	// default filters are for reading, modifications are always unfiltered
+	var state *cProcessor
+	if state, err = eql.prepareSyntaxBuild(&Syntax{
+		Lookup:     true,
+		Unfiltered: true,
+		Keys:       []*SourceKey{{Source: values.Ref(source.name), Key: SourceIdKey, Pos: pos}},
+		Within:     within,
+		Pos:        pos,
+	}); err != nil {
+		return
+	}
+
+	var top sqlbuilder.Table
+	if top, err = state.prepareBuild(); err != nil {
+		return
+	}
+
+	var cond sqlbuilder.Condition
+	if cond, err = state.syntax.Within.make(state); err != nil {
+		return
+	}
+
+	direct := state.plan.top == source.name && len(state.plan.joins) == 0 && len(state.getForeignSources()) == 0
+
+	var subquery string
+	var subargs []interface{}
+	if !direct {
+		// most dialects cannot join other tables in an UPDATE or DELETE, and
+		// some cannot select from the table modified in a subquery unless the
+		// subquery is itself wrapped in a derived table
+		if subquery, subargs, err = eql.builder.
+			Select(top).
+			Columns(table.C(SourceIdKey)).
+			Where(cond).
+			ToSql(); err != nil {
+			return
+		}
+		subquery = strings.TrimSuffix(subquery, eql.dialect.QuerySuffix())
+		subquery = "SELECT " + eql.dialect.QuoteField(SourceIdKey) +
+			" FROM (" + subquery + ") AS " + eql.dialect.QuoteField(gSubqueryAlias)
+		// the marker is given the id column as the builder requires all
+		// columns to be present in the FROM clause
+		cond = table.C(SourceIdKey).In(sqlbuilder.Func(gSubqueryMarker, table.C(SourceIdKey)))
+	}
+
+	if query, argv, err = eql.builder.Delete(table).Where(cond).ToSql(); err != nil {
+		return
+	}
+
+	if !direct {
+		// the marker condition has no values, so the subquery values are the
+		// only ones and its bind variables do not need renumbering
+		query = rxSubqueryMarker.ReplaceAllLiteralString(query, subquery)
+		argv = subargs
+	}
+
+	if len(sets) > 0 {
+		if query, argv, err = eql.prepareUpdateSQL(source, table, query, argv, sets); err != nil {
+			return
+		}
+	}
+
+	query, err = expandMarkers(eql.dialect, query)
+	return
+}
+
+// prepareUpdateSQL returns the UPDATE statement for the sets given, using the
+// WHERE clause of the DELETE statement given
+//
+// The sql builder does not have a dialect specific UPDATE, so the WHERE
+// clause is what follows the DELETE FROM table prefix the builder always
+// produces. The SET values are bound after the WHERE values for dialects with
+// numbered bind variables, otherwise before them
+func (eql *enjinql) prepareUpdateSQL(source *cSource, table sqlbuilder.Table, deleted string, where []interface{}, sets []*cModifySet) (query string, argv []interface{}, err error) {
+	prefix := "DELETE FROM " + eql.dialect.QuoteField(table.Name()) + " WHERE "
+	if !strings.HasPrefix(deleted, prefix) {
+		err = fmt.Errorf("%w: unexpected DELETE statement: %q", ErrBuilderError, deleted)
+		return
+	}
+
+	numbered := eql.dialect.BindVar(1) != eql.dialect.BindVar(2)
+
+	var assignments []string
+	var list []interface{}
+	for _, set := range sets {
+		if _, err = getModifyColumn(source, set.key); err != nil {
+			return
+		}
+		list = append(list, set.value)
+		bind := len(list)
+		if numbered {
+			bind += len(where)
+		}
+		assignments = append(assignments, eql.dialect.QuoteField(strcase.ToSnake(set.key))+" = "+eql.dialect.BindVar(bind))
+	}
+
+	query = "UPDATE " + eql.dialect.QuoteField(table.Name()) + " SET " + strings.Join(assignments, ", ") +
+		" WHERE " + deleted[len(prefix):]
+	if numbered {
+		argv = append(where, list...)
+	} else {
+		argv = append(list, where...)
+	}
+	return
+}
+
+func (c *cSqlTX) Execute(format string, argv ...interface{}) (id int64, affected int64, err error) {
+	var prepared string
+	if prepared, err = PrepareSyntax(format, argv...); err != nil {
+		return
+	}
+
+	var parsed *Modify
+	if parsed, err = ParseModify(prepared); err != nil {
+		if _, ee := ParseSyntax(prepared); ee == nil {
+			err = ErrSelectStatement
+		}
+		return
+	}
+
+	var specific error
+	switch {
+	case parsed.Insert != nil:
+		specific = ErrInsertRow
+	case parsed.Update != nil:
+		specific = ErrUpdateRows
+	default:
+		specific = ErrDeleteRows
+	}
+
+	var query string
+	var args []interface{}
+	c.eql.m.RLock()
+	query, args, err = c.eql.prepareModifySQL(parsed)
+	c.eql.m.RUnlock()
+	if err != nil {
+		err = fmt.Errorf("%w: %w", specific, err)
+		return
+	}
+
+	if parsed.Insert != nil && c.eql.dialect.Name() == "postgresql" {
+		// postgres does not report the last insert id, return the ids
+		// inserted instead
+		if id, affected, err = c.execReturning(query, args...); err != nil {
+			err = fmt.Errorf("%w: %w", specific, err)
+		}
+		return
+	}
+
+	var result sql.Result
+	if result, err = c.tx.Exec(query, args...); err != nil {
+		err = fmt.Errorf("%w: %w", specific, err)
+		return
+	}
+
+	if parsed.Insert != nil {
+		id, _ = result.LastInsertId()
+	} else {
+		c.ids.forget()
+	}
+	affected, err = result.RowsAffected()
+	return
+}
+
+// execReturning performs the INSERT query given with a RETURNING clause for
+// the ids of the rows inserted, returning the last id and the number of rows
+func (c *cSqlTX) execReturning(query string, args ...interface{}) (id, affected int64, err error) {
+	suffix := c.eql.dialect.QuerySuffix()
+	query = strings.TrimSuffix(query, suffix) + " RETURNING " + c.eql.dialect.QuoteField(SourceIdKey) + suffix
+
+	var rows *sql.Rows
+	if rows, err = c.tx.Query(query, args...); err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err = rows.Scan(&id); err != nil {
+			return
+		}
+		affected += 1
+	}
+	err = rows.Err()
+	return
+}
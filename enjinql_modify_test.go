@@ -0,0 +1,148 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+	"github.com/go-corelibs/go-sqlbuilder/dialects"
+)
+
+func TestModifyStatements(t *testing.T) {
+	Convey("modify statements", t, func() {
+
+		config, err := NewConfig("be_eql").
+			AddSource(PageSourceConfig()).
+			Make()
+		SoMsg("config error", err, ShouldBeNil)
+
+		eql, tdb := makeTestEQL(config)
+		defer tdb.Close()
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		for idx, test := range []struct {
+			input string
+			argv  []interface{}
+			id    int64
+			err   error
+		}{
+			{input: `INSERT INTO page (.shasum, .language, .type, .url) VALUES ({1}, "en", "page", "/one")`, argv: []interface{}{"1234567890"}, id: 1},
+			{input: `insert into Page (.Shasum, .Language, .Type, .URL) values ("0123456789", "en", "blog", "/two");`, id: 2},
+			{input: `INSERT INTO nope (.shasum) VALUES ("1")`, err: ErrSourceNotFound},
+			{input: `INSERT INTO page (.nope) VALUES ("1")`, err: ErrColumnNotFound},
+			{input: `INSERT INTO page (.shasum, .url) VALUES ("1")`, err: ErrInvalidSyntax},
+			{input: `LOOKUP .url`, err: ErrSelectStatement},
+		} {
+			id, inserted, ee := tx.Execute(test.input, test.argv...)
+			if test.err != nil {
+				SoMsg(fmt.Sprintf("insert #%d error", idx), fmt.Sprint(ee), ShouldContainSubstring, test.err.Error())
+				continue
+			}
+			SoMsg(fmt.Sprintf("insert #%d error", idx), ee, ShouldBeNil)
+			SoMsg(fmt.Sprintf("insert #%d id", idx), id, ShouldEqual, test.id)
+			SoMsg(fmt.Sprintf("insert #%d affected", idx), inserted, ShouldEqual, 1)
+		}
+
+		// the RETURNING clause used for postgres, which sqlite supports too
+		parsed, err := ParseModify(`INSERT INTO page (.shasum, .url) VALUES ("2345678901", "/three")`)
+		SoMsg("returning parse error", err, ShouldBeNil)
+		query, argv, err := eql.(*enjinql).prepareModifySQL(parsed)
+		SoMsg("returning query error", err, ShouldBeNil)
+		nested, err := tx.Nested()
+		SoMsg("returning nested error", err, ShouldBeNil)
+		id, inserted, err := nested.(*cSqlTrunkTX).execReturning(query, argv...)
+		SoMsg("returning error", err, ShouldBeNil)
+		SoMsg("returning id", id, ShouldEqual, 3)
+		SoMsg("returning affected", inserted, ShouldEqual, 1)
+		SoMsg("returning rollback error", nested.Rollback(), ShouldBeNil)
+
+		_, affected, err := tx.Execute(`UPDATE page SET .type = "post", .archetype = NULL WITHIN .type == {1}`, "blog")
+		SoMsg("update error", err, ShouldBeNil)
+		SoMsg("update affected", affected, ShouldEqual, 1)
+		_, _, err = tx.Execute(`UPDATE page SET .type = .url WITHIN .type == "page"`)
+		SoMsg("update source key value error", fmt.Sprint(err), ShouldContainSubstring, ErrModifyValue.Error())
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err := eql.Perform(`LOOKUP .url, .type ORDER BY .url`)
+		SoMsg("lookup error", err, ShouldBeNil)
+		SoMsg("lookup results", results, ShouldEqual, clContext.Contexts{
+			{"url": "/one", "type": "page"},
+			{"url": "/two", "type": "post"},
+		})
+
+		_, err = eql.Parse(`DELETE FROM page WITHIN .type == "post"`)
+		SoMsg("parse modify error", err, ShouldEqual, ErrModifyStatement)
+
+		tx, err = eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		_, affected, err = tx.Execute(`DELETE FROM page WITHIN .type == "post"`)
+		SoMsg("delete error", err, ShouldBeNil)
+		SoMsg("delete affected", affected, ShouldEqual, 1)
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err = eql.Perform(`LOOKUP .url`)
+		SoMsg("lookup error", err, ShouldBeNil)
+		SoMsg("lookup results", results, ShouldEqual, clContext.Contexts{
+			{"url": "/one"},
+		})
+
+		Convey("with joined sources", func() {
+			qf, qdb := makeQfEQL()
+			defer qdb.Close()
+
+			parsed, err := ParseModify(`DELETE FROM page_words WITHIN word.word == "quote"`)
+			SoMsg("parse error", err, ShouldBeNil)
+			query, argv, err := qf.(*enjinql).prepareModifySQL(parsed)
+			SoMsg("delete sql error", err, ShouldBeNil)
+			SoMsg("delete sql", query, ShouldEqual, `DELETE FROM "qf_eql_page_words" WHERE "qf_eql_page_words"."id" IN ( SELECT "id" FROM (SELECT "qf_eql_page_words"."id" FROM "qf_eql_page" INNER JOIN "qf_eql_page_words" ON "qf_eql_page"."id"="qf_eql_page_words"."page_id" INNER JOIN "qf_eql_word" ON "qf_eql_page_words"."word_id"="qf_eql_word"."id" WHERE "qf_eql_word"."word"=?) AS "eql_ids" );`)
+			SoMsg("delete sql argv", argv, ShouldEqual, []interface{}{"quote"})
+
+			pg, err := New(makeQfConfig(), qdb.DBH(), dialects.Postgresql{}, SkipCreateTable, SkipCreateIndex)
+			SoMsg("postgresql enjinql error", err, ShouldBeNil)
+			parsed, err = ParseModify(`UPDATE page_words SET .hits = 10 WITHIN (word.word == "quote") AND (page.type == "quote")`)
+			SoMsg("parse error", err, ShouldBeNil)
+			query, argv, err = pg.(*enjinql).prepareModifySQL(parsed)
+			SoMsg("postgresql update sql error", err, ShouldBeNil)
+			SoMsg("postgresql update sql", query, ShouldEqual, `UPDATE "qf_eql_page_words" SET "hits" = $3 WHERE "qf_eql_page_words"."id" IN ( SELECT "id" FROM (SELECT "qf_eql_page_words"."id" FROM "qf_eql_page" INNER JOIN "qf_eql_page_words" ON "qf_eql_page"."id"="qf_eql_page_words"."page_id" INNER JOIN "qf_eql_word" ON "qf_eql_page_words"."word_id"="qf_eql_word"."id" WHERE "qf_eql_word"."word"=$1 AND "qf_eql_page"."type"=$2) AS "eql_ids" );`)
+			SoMsg("postgresql update sql argv", argv, ShouldEqual, []interface{}{"quote", "quote", 10})
+
+			tx, err := qf.SqlBegin()
+			SoMsg("sql begin err", err, ShouldBeNil)
+			_, affected, err := tx.Execute(`UPDATE page_words SET .hits = 10 WITHIN word.word == {1}`, "this")
+			SoMsg("joined update error", err, ShouldBeNil)
+			SoMsg("joined update affected", affected, ShouldEqual, 2)
+			_, affected, err = tx.Execute(`DELETE FROM page_words WITHIN word.word == "quote"`)
+			SoMsg("joined delete error", err, ShouldBeNil)
+			SoMsg("joined delete affected", affected, ShouldEqual, 2)
+			SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+			_, results, err := qf.Perform(`LOOKUP page_words.id WITHIN word.word == "quote"`)
+			SoMsg("lookup error", err, ShouldBeNil)
+			SoMsg("lookup results", len(results), ShouldEqual, 0)
+			_, results, err = qf.Perform(`LOOKUP page_words.hits WITHIN word.word == "this"`)
+			SoMsg("lookup error", err, ShouldBeNil)
+			SoMsg("lookup results", results, ShouldEqual, clContext.Contexts{
+				{"hits": int64(10)},
+				{"hits": int64(10)},
+			})
+		})
+
+	})
+}
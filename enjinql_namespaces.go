@@ -0,0 +1,224 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+	"github.com/go-corelibs/maps"
+)
+
+// Namespaces is the interface for managing multiple EnjinQL instances sharing
+// one sql.DB, where each instance is a namespace named by its Config.Prefix,
+// snake cased the same as the table names of its sources
+//
+// The EnjinQL instances of a Namespaces manager only see their own sources
+// unless a source key is qualified with another namespace, for example:
+//
+//	LOOKUP .url, site_a:page.url AS other WITHIN site_a:page.shasum == .shasum
+//
+// Qualified sources are joined using the WITHIN constraints relating them to
+// the other sources of the statement
+type Namespaces interface {
+	// Create validates the Config given and returns a new EnjinQL instance
+	// for the Config.Prefix namespace, which must be unique and not empty.
+	// The tables and indexes are created unless the options given skip them
+	Create(c *Config, options ...Option) (eql EnjinQL, err error)
+
+	// Get returns the EnjinQL instance of the namespace given
+	Get(prefix string) (eql EnjinQL, ok bool)
+
+	// List returns the sorted list of namespaces
+	List() (prefixes []string)
+
+	// Drop closes the EnjinQL instance of the namespace given and drops all
+	// of its tables
+	Drop(prefix string) (err error)
+
+	// DBH returns the shared sql.DB instance
+	DBH() *sql.DB
+
+	// Close closes all namespace EnjinQL instances and the shared sql.DB
+	Close() (err error)
+}
+
+type cNamespaces struct {
+	dbh       *sql.DB
+	dialect   sqlbuilder.Dialect
+	instances map[string]*enjinql
+
+	m *sync.RWMutex
+}
+
+// NewNamespaces returns a new Namespaces manager for the sql.DB given
+func NewNamespaces(dbh *sql.DB, dialect sqlbuilder.Dialect) (ns Namespaces, err error) {
+	if dbh == nil {
+		err = fmt.Errorf("dbh is required")
+		return
+	} else if dialect == nil {
+		err = fmt.Errorf("dialect is required")
+		return
+	}
+	ns = &cNamespaces{
+		dbh:       dbh,
+		dialect:   dialect,
+		instances: make(map[string]*enjinql),
+		m:         &sync.RWMutex{},
+	}
+	return
+}
+
+func withNamespaces(ns *cNamespaces) Option {
+	return func(o *option) (err error) {
+		o.namespaces = ns
+		return
+	}
+}
+
+func (n *cNamespaces) Create(c *Config, options ...Option) (eql EnjinQL, err error) {
+	if c == nil {
+		err = fmt.Errorf("config is required")
+		return
+	} else if c.Prefix == "" {
+		err = ErrNamespacePrefix
+		return
+	}
+
+	// prefixes differing only by case share the same table names
+	key := strcase.ToSnake(c.Prefix)
+	if _, present := n.get(key); present {
+		err = fmt.Errorf("%w: %q (%q)", ErrNamespaceExists, c.Prefix, key)
+		return
+	}
+
+	// the instance is made without holding the lock because validating its
+	// saved queries looks up the other namespaces
+	if eql, err = New(c, n.dbh, n.dialect, append(options, withNamespaces(n))...); err != nil {
+		return
+	}
+	instance := eql.(*enjinql)
+
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	if _, present := n.instances[key]; present {
+		// created concurrently, discard this instance without closing it
+		// because that would remove the other one
+		instance.m.Lock()
+		instance.closed = true
+		instance.m.Unlock()
+		eql = nil
+		err = fmt.Errorf("%w: %q (%q)", ErrNamespaceExists, c.Prefix, key)
+		return
+	}
+
+	n.instances[key] = instance
+	return
+}
+
+func (n *cNamespaces) Get(prefix string) (eql EnjinQL, ok bool) {
+	var instance *enjinql
+	if instance, ok = n.get(prefix); ok {
+		eql = instance
+	}
+	return
+}
+
+func (n *cNamespaces) get(prefix string) (eql *enjinql, ok bool) {
+	n.m.RLock()
+	defer n.m.RUnlock()
+	eql, ok = n.instances[strcase.ToSnake(prefix)]
+	return
+}
+
+func (n *cNamespaces) List() (prefixes []string) {
+	n.m.RLock()
+	defer n.m.RUnlock()
+	return maps.SortedKeys(n.instances)
+}
+
+func (n *cNamespaces) Drop(prefix string) (err error) {
+	eql, ok := n.get(prefix)
+	if !ok {
+		err = fmt.Errorf("%w: %q", ErrNamespaceNotFound, prefix)
+		return
+	}
+
+	var tx *sql.Tx
+	if tx, err = n.dbh.Begin(); err != nil {
+		return
+	}
+
+	// drop in reverse order so that child sources go before their parents
+	for idx := len(eql.config.Sources) - 1; idx >= 0; idx-- {
+		name := eql.sources.formal(eql.config.Sources[idx].Name)
+		query := "DROP TABLE IF EXISTS " + n.dialect.QuoteField(name) + n.dialect.QuerySuffix()
+		if _, err = tx.Exec(query); err != nil {
+			_ = tx.Rollback()
+			err = fmt.Errorf("%w: %q - %w", ErrDropTable, name, err)
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	return eql.Close()
+}
+
+func (n *cNamespaces) remove(prefix string) {
+	n.m.Lock()
+	defer n.m.Unlock()
+	delete(n.instances, strcase.ToSnake(prefix))
+}
+
+func (n *cNamespaces) DBH() *sql.DB {
+	return n.dbh
+}
+
+func (n *cNamespaces) Close() (err error) {
+	n.m.RLock()
+	var instances []*enjinql
+	for _, prefix := range maps.SortedKeys(n.instances) {
+		instances = append(instances, n.instances[prefix])
+	}
+	n.m.RUnlock()
+
+	for _, eql := range instances {
+		_ = eql.Close()
+	}
+
+	return n.dbh.Close()
+}
+
+// getNamespaceSources returns the sources of the namespace given, which is
+// this instance when the namespace is this instance's Config.Prefix
+func (eql *enjinql) getNamespaceSources(prefix string) (sources *cSources, ok bool) {
+	if prefix == eql.config.Prefix {
+		return eql.sources, true
+	} else if eql.option.namespaces != nil {
+		var other *enjinql
+		if other, ok = eql.option.namespaces.get(prefix); ok {
+			sources = other.sources
+		}
+	}
+	return
+}
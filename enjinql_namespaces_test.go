@@ -0,0 +1,142 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+	"github.com/go-corelibs/go-sqlbuilder/dialects"
+	"github.com/go-corelibs/tdata"
+	"github.com/go-corelibs/testdb"
+)
+
+func TestNamespaces(t *testing.T) {
+	Convey("namespaces", t, func() {
+
+		tdb, err := testdb.NewTestDBWith(tdata.TempFile("", "enjinql.*.namespaces.db"))
+		SoMsg("sqlite db open error", err, ShouldBeNil)
+		defer tdb.Close()
+
+		ns, err := NewNamespaces(tdb.DBH(), dialects.Sqlite{})
+		SoMsg("new namespaces error", err, ShouldBeNil)
+
+		siteA, _ := NewConfig("site_a").AddSource(PageSourceConfig()).Make()
+		siteB, _ := NewConfig("site_b").AddSource(PageSourceConfig()).Make()
+
+		a, err := ns.Create(siteA)
+		SoMsg("create site_a error", err, ShouldBeNil)
+		b, err := ns.Create(siteB)
+		SoMsg("create site_b error", err, ShouldBeNil)
+		_, err = ns.Create(siteA)
+		SoMsg("create site_a again error", err, ShouldWrap, ErrNamespaceExists)
+		camel := *siteA
+		camel.Prefix = "siteA"
+		_, err = ns.Create(&camel)
+		SoMsg("create siteA error", err, ShouldWrap, ErrNamespaceExists)
+		found, ok := ns.Get("siteA")
+		SoMsg("get siteA", ok, ShouldBeTrue)
+		SoMsg("get siteA instance", found, ShouldEqual, a)
+		unprefixed, _ := NewConfig().AddSource(PageSourceConfig()).Make()
+		_, err = ns.Create(unprefixed)
+		SoMsg("create unprefixed error", err, ShouldEqual, ErrNamespacePrefix)
+		SoMsg("namespaces list", ns.List(), ShouldEqual, []string{"site_a", "site_b"})
+
+		created, _ := time.Parse("2006-01-02 15:04", "2024-03-17 11:25")
+		for _, insert := range []struct {
+			eql    EnjinQL
+			values [][]interface{}
+		}{
+			{a, [][]interface{}{
+				{"1234567890", "en", "page", "", created, created, "/a/one", `["one"]`},
+				{"0123456789", "en", "page", "", created, created, "/a/two", `["two"]`},
+			}},
+			{b, [][]interface{}{
+				{"1234567890", "en", "page", "", created, created, "/b/one", `["one"]`},
+			}},
+		} {
+			tx, ee := insert.eql.SqlBegin()
+			SoMsg("sql begin err", ee, ShouldBeNil)
+			for _, values := range insert.values {
+				_, ee = tx.TX().Insert("page", values...)
+				SoMsg("insert error", ee, ShouldBeNil)
+			}
+			SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+		}
+
+		_, results, err := a.Perform(`LOOKUP .Url ORDER BY .Url`)
+		SoMsg("own lookup error", err, ShouldBeNil)
+		SoMsg("own lookup", results, ShouldEqual, clContext.Contexts{
+			{"url": "/a/one"},
+			{"url": "/a/two"},
+		})
+
+		query, _, err := b.ToSQL(`LOOKUP .Url, site_a:page.Url AS other WITHIN site_a:page.Shasum == .Shasum`)
+		SoMsg("cross lookup sql error", err, ShouldBeNil)
+		SoMsg("cross lookup sql", query, ShouldEqual, `SELECT "site_b_page"."url", "site_a_page"."url" AS "other" FROM "site_b_page" INNER JOIN "site_a_page" ON "site_a_page"."shasum"="site_b_page"."shasum" WHERE "site_a_page"."shasum"="site_b_page"."shasum";`)
+
+		_, results, err = b.Perform(`LOOKUP .Url, site_a:page.Url AS other WITHIN site_a:page.Shasum == .Shasum`)
+		SoMsg("cross lookup error", err, ShouldBeNil)
+		SoMsg("cross lookup", results, ShouldEqual, clContext.Contexts{
+			{"url": "/b/one", "other": "/a/one"},
+		})
+
+		_, results, err = b.Perform(`LOOKUP site_a:.Url WITHIN site_a:.Shasum == "0123456789"`)
+		SoMsg("foreign only lookup error", err, ShouldBeNil)
+		SoMsg("foreign only lookup", results, ShouldEqual, clContext.Contexts{
+			{"url": "/a/two"},
+		})
+
+		_, _, err = b.ToSQL(`LOOKUP .Url, site_a:page.Url AS other`)
+		SoMsg("unrelated namespace error", err, ShouldWrap, ErrNamespaceJoin)
+		_, _, err = b.ToSQL(`LOOKUP .Url WITHIN site_c:page.Shasum == .Shasum`)
+		SoMsg("unknown namespace error", err, ShouldWrap, ErrNamespaceNotFound)
+
+		siteC, _ := NewConfig("site_c").
+			AddSource(PageSourceConfig()).
+			AddQuery("cross", `LOOKUP .Url WITHIN site_a:page.Shasum == .Shasum`).
+			Make()
+		done := make(chan error, 1)
+		go func() {
+			_, ee := ns.Create(siteC)
+			done <- ee
+		}()
+		select {
+		case ee := <-done:
+			SoMsg("create with a cross namespace query error", ee, ShouldBeNil)
+		case <-time.After(5 * time.Second):
+			t.Fatal("create with a cross namespace query did not return")
+		}
+		SoMsg("namespaces list after create", ns.List(), ShouldEqual, []string{"site_a", "site_b", "site_c"})
+
+		SoMsg("drop site_a error", ns.Drop("site_a"), ShouldBeNil)
+		SoMsg("drop site_a again error", ns.Drop("site_a"), ShouldWrap, ErrNamespaceNotFound)
+		SoMsg("namespaces list after drop", ns.List(), ShouldEqual, []string{"site_b", "site_c"})
+		SoMsg("site_a closed", a.Ready(), ShouldEqual, sql.ErrConnDone)
+		SoMsg("site_b ready", b.Ready(), ShouldBeNil)
+
+		_, _, err = b.Perform(`LOOKUP .Url, site_a:page.Url AS other WITHIN site_a:page.Shasum == .Shasum`)
+		SoMsg("dropped namespace error", err, ShouldWrap, ErrNamespaceNotFound)
+
+		got, ok := ns.Get("site_b")
+		SoMsg("get site_b", ok, ShouldBeTrue)
+		SoMsg("get site_b instance", got, ShouldEqual, b)
+
+	})
+}
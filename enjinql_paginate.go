@@ -0,0 +1,123 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/go-corelibs/context"
+)
+
+// Paginated is the result of a Paginate call
+type Paginated struct {
+	// Columns is the column order of the Results
+	Columns []string `json:"columns"`
+	// Results is the page of results requested
+	Results context.Contexts `json:"results"`
+	// Total is the total number of results across all pages
+	Total int `json:"total"`
+	// Page is the page number of these Results, starting from one
+	Page int `json:"page"`
+	// PerPage is the maximum number of Results per page
+	PerPage int `json:"perPage"`
+	// Pages is the total number of pages
+	Pages int `json:"pages"`
+	// HasNext is true when there is a page after this one
+	HasNext bool `json:"hasNext"`
+	// HasPrev is true when there is a page before this one
+	HasPrev bool `json:"hasPrev"`
+}
+
+func (eql *enjinql) Paginate(format string, page, perPage int, argv ...interface{}) (paginated *Paginated, err error) {
+	return eql.paginate(eql.withReadTX, format, page, perPage, argv...)
+}
+
+// paginate is Paginate with the count and page queries performed by the run
+// func given
+func (eql *enjinql) paginate(run func(fn func(db SqlDB) (err error)) (err error), format string, page, perPage int, argv ...interface{}) (paginated *Paginated, err error) {
+	if err = eql.Ready(); err != nil {
+		return
+	} else if perPage <= 0 {
+		err = fmt.Errorf("%w: %d", ErrPerPage, perPage)
+		return
+	} else if page < 1 {
+		page = 1
+	}
+
+	var parsed *Syntax
+	if parsed, err = eql.Parse(format, argv...); err != nil {
+		return
+	} else if parsed.Count {
+		err = fmt.Errorf("%w: %w", ErrInvalidSyntax, ErrPaginateCount)
+		return
+	}
+
+	// the count and the page are derived from the same parsed statement,
+	// the count does not need any of the ordering or windowing clauses
+	counted := *parsed
+	counted.OrderBy, counted.After, counted.Offset, counted.Limit = nil, nil, nil, nil
+
+	offset := (page - 1) * perPage
+	paged := *parsed
+	paged.After, paged.Offset, paged.Limit = nil, &offset, &perPage
+
+	if parsed.Per != nil {
+		// the LIMIT is of each PER key value and the rows are ranked by the
+		// ORDER BY, so both are counted and the page is of all the rows
+		counted.OrderBy, counted.Limit, counted.maxRows, counted.skipRows = parsed.OrderBy, parsed.Limit, nil, nil
+		paged.Offset, paged.Limit, paged.maxRows, paged.skipRows = nil, parsed.Limit, &perPage, &offset
+	}
+
+	var countQuery, pageQuery string
+	var countArgs, pageArgs []interface{}
+	if countQuery, countArgs, err = eql.ParsedToSql(&counted); err != nil {
+		return
+	} else if pageQuery, pageArgs, err = eql.ParsedToSql(&paged); err != nil {
+		return
+	}
+
+	suffix := eql.dialect.QuerySuffix()
+	countQuery = strings.TrimSuffix(countQuery, suffix)
+	countQuery = "SELECT COUNT(*) FROM (" + countQuery + ") AS " + eql.dialect.QuoteField("eql_paginate") + suffix
+
+	var total int
+	var columns []string
+	var results context.Contexts
+	if err = run(func(db SqlDB) (err error) {
+		if err = db.QueryRow(countQuery, countArgs...).Scan(&total); err != nil {
+			return
+		}
+		var rows *sql.Rows
+		if rows, err = db.Query(pageQuery, pageArgs...); err == nil {
+			columns, results = scanContexts(rows)
+		}
+		return
+	}); err != nil {
+		return
+	}
+
+	paginated = &Paginated{
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+		Pages:   (total + perPage - 1) / perPage,
+		HasPrev: page > 1,
+	}
+	paginated.Columns, paginated.Results = columns, results
+	paginated.HasNext = page < paginated.Pages
+	return
+}
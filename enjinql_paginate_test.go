@@ -0,0 +1,95 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+)
+
+func TestPaginate(t *testing.T) {
+	Convey("pagination", t, func() {
+
+		eql, _ := makeQfEQL()
+		defer eql.Close()
+
+		statement := `LOOKUP .Shasum WITHIN word.Word ^= {1} ORDER BY .Shasum`
+
+		paginated, err := eql.Paginate(statement, 1, 1, "th")
+		SoMsg("first page error", err, ShouldBeNil)
+		SoMsg("first page", paginated, ShouldEqual, &Paginated{
+			Columns: []string{"shasum"},
+			Results: clContext.Contexts{{"shasum": "0102000405"}},
+			Total:   2,
+			Page:    1,
+			PerPage: 1,
+			Pages:   2,
+			HasNext: true,
+			HasPrev: false,
+		})
+
+		paginated, err = eql.Paginate(statement, 2, 1, "th")
+		SoMsg("last page error", err, ShouldBeNil)
+		SoMsg("last page", paginated, ShouldEqual, &Paginated{
+			Columns: []string{"shasum"},
+			Results: clContext.Contexts{{"shasum": "1122334455"}},
+			Total:   2,
+			Page:    2,
+			PerPage: 1,
+			Pages:   2,
+			HasNext: false,
+			HasPrev: true,
+		})
+
+		paginated, err = eql.Paginate(statement, 3, 10, "nope")
+		SoMsg("empty page error", err, ShouldBeNil)
+		SoMsg("empty page total", paginated.Total, ShouldEqual, 0)
+		SoMsg("empty page pages", paginated.Pages, ShouldEqual, 0)
+		SoMsg("empty page results", paginated.Results, ShouldBeEmpty)
+
+		_, err = eql.Paginate(statement, 1, 0, "th")
+		SoMsg("per-page error", err, ShouldWrap, ErrPerPage)
+
+		paginated, err = eql.Paginate(`LOOKUP .Url LIMIT 1 PER .Type`, 1, 10)
+		SoMsg("per page error", err, ShouldBeNil)
+		SoMsg("per page total", paginated.Total, ShouldEqual, 1)
+		SoMsg("per page results", len(paginated.Results), ShouldEqual, 1)
+
+		perStatement := `LOOKUP word.Letter, word.Word ORDER BY word.Word LIMIT 1 PER word.Letter`
+		paginated, err = eql.Paginate(perStatement, 2, 3)
+		SoMsg("per second page error", err, ShouldBeNil)
+		SoMsg("per second page", paginated, ShouldEqual, &Paginated{
+			Columns: []string{"letter", "word"},
+			Results: clContext.Contexts{
+				{"letter": "i", "word": "is"},
+				{"letter": "o", "word": "of"},
+				{"letter": "q", "word": "quote"},
+			},
+			Total:   7,
+			Page:    2,
+			PerPage: 3,
+			Pages:   3,
+			HasNext: true,
+			HasPrev: true,
+		})
+
+		_, err = eql.Paginate(`LOOKUP COUNT .Shasum`, 1, 10)
+		SoMsg("count error", err, ShouldWrap, ErrPaginateCount)
+
+	})
+}
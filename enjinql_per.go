@@ -0,0 +1,136 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+)
+
+const (
+	// PerKeyPrefix is the alias prefix used for the subqueries and row
+	// numbers of statements using LIMIT n PER <key>
+	PerKeyPrefix = "eql_per_"
+)
+
+var (
+	// gWindowDialects are the sqlbuilder dialect names that support window
+	// functions, all others use a correlated subquery to rank rows
+	gWindowDialects = map[string]bool{
+		"sqlite3":    true,
+		"postgresql": true,
+		"mysql":      true,
+	}
+)
+
+// makePer returns the outer query selecting at most limit rows of the inner
+// query given for each PER key value, ranked by the ORDER BY values and then
+// the key source id, and at most maxRows in total when not nil, skipping the
+// first skipRows when also not nil. Dialects with
+// window functions use ROW_NUMBER() and all others use a correlated subquery
+// counting the rows ranked before each row
+func (w *cWrapSelect) makePer(d sqlbuilder.Dialect, inner string, args []interface{}, limit int, maxRows, skipRows *int) (query string, argv []interface{}) {
+	inner = strings.TrimSuffix(inner, d.QuerySuffix())
+
+	quote := func(table, key string) string {
+		return d.QuoteField(table) + "." + d.QuoteField(key)
+	}
+	direction := func(wo cWrapOrder) string {
+		if wo.desc {
+			return " DESC"
+		}
+		return " ASC"
+	}
+	selectFrom := func(table string) string {
+		var selected []string
+		for _, wk := range w.keys {
+			selected = append(selected, quote(table, wk.key)+" AS "+d.QuoteField(wk.name))
+		}
+		return "SELECT " + strings.Join(selected, ", ")
+	}
+
+	outer := PerKeyPrefix + "a"
+	other := PerKeyPrefix + "b"
+
+	if gWindowDialects[d.Name()] {
+		// SELECT b.<keys> FROM (
+		//   SELECT a.*, ROW_NUMBER() OVER (PARTITION BY a.per ORDER BY a.order...) AS row FROM (<inner>) AS a
+		// ) AS b WHERE b.row <= <limit> ORDER BY b.order...
+
+		var window, ordered []string
+		for _, wo := range w.order {
+			window = append(window, quote(outer, wo.key)+direction(wo))
+			ordered = append(ordered, quote(other, wo.key)+direction(wo))
+		}
+
+		query = selectFrom(other)
+		query += " FROM ( SELECT " + d.QuoteField(outer) + ".*, ROW_NUMBER() OVER ("
+		query += "PARTITION BY " + quote(outer, w.per)
+		query += " ORDER BY " + strings.Join(window, ", ")
+		query += ") AS " + d.QuoteField(PerKeyPrefix+"row")
+		query += " FROM ( " + inner + " ) AS " + d.QuoteField(outer)
+		query += " ) AS " + d.QuoteField(other)
+		query += " WHERE " + quote(other, PerKeyPrefix+"row") + "<=" + strconv.Itoa(limit)
+		query += " ORDER BY " + strings.Join(ordered, ", ")
+		if maxRows != nil {
+			query += " LIMIT " + strconv.Itoa(*maxRows)
+			if skipRows != nil {
+				query += " OFFSET " + strconv.Itoa(*skipRows)
+			}
+		}
+		query += d.QuerySuffix()
+		argv = args
+		return
+	}
+
+	// SELECT a.<keys> FROM (<inner>) AS a WHERE (
+	//   SELECT COUNT(*) FROM (<inner>) AS b WHERE b.per = a.per AND <b ranks before a>
+	// ) < <limit> ORDER BY a.order...
+
+	var branches, ordered []string
+	for idx, wo := range w.order {
+		var conditions []string
+		for _, prev := range w.order[:idx] {
+			conditions = append(conditions, quote(other, prev.key)+"="+quote(outer, prev.key))
+		}
+		before := "<"
+		if wo.desc {
+			before = ">"
+		}
+		conditions = append(conditions, quote(other, wo.key)+before+quote(outer, wo.key))
+		branches = append(branches, "( "+strings.Join(conditions, " AND ")+" )")
+		ordered = append(ordered, quote(outer, wo.key)+direction(wo))
+	}
+
+	query = selectFrom(outer)
+	query += " FROM ( " + inner + " ) AS " + d.QuoteField(outer)
+	query += " WHERE ( SELECT COUNT(*) FROM ( " + inner + " ) AS " + d.QuoteField(other)
+	query += " WHERE " + quote(other, w.per) + "=" + quote(outer, w.per)
+	query += " AND ( " + strings.Join(branches, " OR ") + " )"
+	query += " )<" + strconv.Itoa(limit)
+	query += " ORDER BY " + strings.Join(ordered, ", ")
+	if maxRows != nil {
+		query += " LIMIT " + strconv.Itoa(*maxRows)
+		if skipRows != nil {
+			query += " OFFSET " + strconv.Itoa(*skipRows)
+		}
+	}
+	query += d.QuerySuffix()
+	// the inner query is present twice
+	argv = append(append(argv, args...), args...)
+	return
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+	"github.com/go-corelibs/go-sqlbuilder/dialects"
+	"github.com/go-corelibs/testdb"
+)
+
+func TestLimitPer(t *testing.T) {
+	Convey("top-n per group", t, func() {
+
+		eql, _ := makeQfEQL()
+		defer eql.Close()
+
+		expected := clContext.Contexts{
+			{"shasum": "1122334455", "word": "a"},
+			{"shasum": "0102000405", "word": "and"},
+			{"shasum": "0102000405", "word": "another"},
+			{"shasum": "1122334455", "word": "contents"},
+		}
+
+		_, results, err := eql.Perform(`LOOKUP .Shasum, word.Word ORDER BY word.Word LIMIT 2 PER .Shasum`)
+		SoMsg("window error", err, ShouldBeNil)
+		SoMsg("window results", results, ShouldEqual, expected)
+
+		// sqlite supports both strategies, confirm the correlated subquery
+		gWindowDialects["sqlite3"] = false
+		_, results, err = eql.Perform(`LOOKUP .Shasum, word.Word ORDER BY word.Word LIMIT 2 PER .Shasum`)
+		gWindowDialects["sqlite3"] = true
+		SoMsg("subquery error", err, ShouldBeNil)
+		SoMsg("subquery results", results, ShouldEqual, expected)
+
+		_, results, err = eql.Perform(`LOOKUP .Shasum, word.Word AS w ORDER BY word.Word DESC LIMIT 1 PER .Shasum`)
+		SoMsg("descending error", err, ShouldBeNil)
+		SoMsg("descending results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "0102000405", "w": "this"},
+			{"shasum": "1122334455", "w": "this"},
+		})
+
+		columns, results, err := eql.Perform(`LOOKUP .ID, word.ID ORDER BY word.Word LIMIT 1 PER .Shasum`)
+		SoMsg("same names error", err, ShouldBeNil)
+		SoMsg("same names columns", columns, ShouldEqual, []string{"id", "id"})
+		SoMsg("same names results", len(results), ShouldEqual, 2)
+		query, _, err := eql.ToSQL(`LOOKUP .ID, word.ID ORDER BY word.Word LIMIT 1 PER .Shasum`)
+		SoMsg("same names query error", err, ShouldBeNil)
+		SoMsg("same names query", query, ShouldStartWith, `SELECT "eql_per_b"."eql_row_0" AS "id", "eql_per_b"."eql_row_1" AS "id" FROM`)
+
+		tdb, err := testdb.NewTestDB()
+		SoMsg("mysql test db error", err, ShouldBeNil)
+		defer tdb.Close()
+		mysql, err := New(makeQfConfig(), tdb.DBH(), dialects.MySql{}, SkipCreateTable, SkipCreateIndex)
+		SoMsg("mysql enjinql error", err, ShouldBeNil)
+		query, _, err = mysql.ToSQL(`LOOKUP .Shasum, word.Word ORDER BY word.Word LIMIT 2 PER .Shasum`)
+		SoMsg("mysql window error", err, ShouldBeNil)
+		SoMsg("mysql window query", query, ShouldContainSubstring, "ROW_NUMBER() OVER (PARTITION BY `eql_per_a`.`eql_row_2` ORDER BY `eql_per_a`.`eql_row_3` ASC, `eql_per_a`.`eql_row_4` ASC)")
+
+	})
+}
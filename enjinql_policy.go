@@ -0,0 +1,409 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/iancoleman/strcase"
+
+	"github.com/go-corelibs/context"
+)
+
+// Policy restricts the statements accepted by ParseSandboxed and
+// PerformSandboxed, for untrusted end-user EQL input
+//
+// The zero Policy allows all sources, keys and operators without any limits,
+// and forbids ORDER BY RANDOM(), the query key (which includes all QUERY
+// statements), UNFILTERED statements and the sources of other namespaces
+type Policy struct {
+	// Sources are the source names allowed, all sources are allowed when
+	// empty. Sources of other namespaces are named with the namespace, for
+	// example: site_a:page
+	Sources []string
+	// Keys are the key names allowed for each source, all keys are allowed
+	// for sources not present
+	Keys map[string][]string
+	// Operators are the constraint operators allowed, all operators are
+	// allowed when empty. Operators are given as written in EQL, for example:
+	// ==, !=, ^=, NOT LIKE, IN and NOT IN
+	Operators []string
+
+	// MaxJoins is the maximum number of tables joined, zero is unlimited
+	MaxJoins int
+	// MaxInList is the maximum number of IN list values, zero is unlimited
+	MaxInList int
+	// MaxLimit is the maximum LIMIT, zero is unlimited. Statements without a
+	// LIMIT are given this LIMIT unless RequireLimit is set and statements
+	// using LIMIT n PER <key> select at most this many rows in total
+	MaxLimit int
+	// RequireLimit rejects statements without a LIMIT, except for COUNT
+	// statements without a GROUP BY
+	RequireLimit bool
+
+	// AllowRandom allows ORDER BY RANDOM()
+	AllowRandom bool
+	// AllowStub allows the query key of the primary source (see
+	// Config.QueryKey), which is the page stub key by default and is the
+	// complete page context. QUERY statements always select the query key
+	AllowStub bool
+	// AllowUnfiltered allows UNFILTERED statements
+	AllowUnfiltered bool
+	// AllowNamespaces allows the sources of other namespaces
+	AllowNamespaces bool
+}
+
+// PolicyError is the error returned for Policy violations, naming the
+// position and text of the offending syntax node
+type PolicyError struct {
+	Pos      lexer.Position
+	Node     string
+	Specific error
+}
+
+func newPolicyError(pos lexer.Position, node string, err error) error {
+	return &PolicyError{
+		Pos:      pos,
+		Node:     node,
+		Specific: err,
+	}
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s %s: %s (%q)", e.Pos.String(), ErrPolicyViolation, e.Specific, e.Node)
+}
+
+func (e *PolicyError) Unwrap() []error {
+	return []error{ErrPolicyViolation, e.Specific}
+}
+
+// canonicalOperator returns the operator given in upper case, with the short
+// form negation spelled out and the alternate not-equal normalized
+func canonicalOperator(op string) string {
+	op = strings.Join(strings.Fields(strings.ToUpper(op)), " ")
+	if op == "<>" {
+		return "!="
+	} else if op != "!=" && strings.HasPrefix(op, "!") {
+		return "NOT " + strings.TrimSpace(op[1:])
+	}
+	return op
+}
+
+type cPolicyCheck struct {
+	policy  *Policy
+	eql     *enjinql
+	sources map[string]struct{}
+	keys    map[string]map[string]struct{}
+	ops     map[string]struct{}
+}
+
+func newPolicyCheck(eql *enjinql, policy *Policy) (pc *cPolicyCheck) {
+	pc = &cPolicyCheck{
+		policy:  policy,
+		eql:     eql,
+		sources: make(map[string]struct{}),
+		keys:    make(map[string]map[string]struct{}),
+		ops:     make(map[string]struct{}),
+	}
+	for _, name := range policy.Sources {
+		pc.sources[name] = struct{}{}
+	}
+	for name, keys := range policy.Keys {
+		pc.keys[name] = make(map[string]struct{})
+		for _, key := range keys {
+			pc.keys[name][key] = struct{}{}
+		}
+	}
+	for _, op := range policy.Operators {
+		pc.ops[canonicalOperator(op)] = struct{}{}
+	}
+	return
+}
+
+// getNamespace returns the EnjinQL instance of the namespace given, which is
+// this instance when the namespace is nil or this instance's Config.Prefix
+func (pc *cPolicyCheck) getNamespace(namespace *string) (eql *enjinql, ok bool) {
+	if namespace == nil || *namespace == pc.eql.config.Prefix {
+		return pc.eql, true
+	} else if pc.eql.option.namespaces != nil {
+		eql, ok = pc.eql.option.namespaces.get(*namespace)
+	}
+	return
+}
+
+func (pc *cPolicyCheck) checkKey(pos lexer.Position, node string, namespace, source *string, key string) (err error) {
+	var name string
+	var isQueryKey bool
+	key = strcase.ToSnake(key)
+	if eql, ok := pc.getNamespace(namespace); ok {
+		primary := eql.sources.getPrimarySourceName()
+		if source != nil && *source != "" {
+			name = strcase.ToSnake(*source)
+		} else {
+			name = primary
+		}
+		isQueryKey = name == primary && key == eql.config.GetQueryKey()
+	} else if source != nil && *source != "" {
+		name = strcase.ToSnake(*source)
+	}
+
+	if namespace != nil && *namespace != pc.eql.config.Prefix {
+		if !pc.policy.AllowNamespaces {
+			return newPolicyError(pos, node, ErrPolicyNamespace)
+		}
+		name = *namespace + ":" + name
+	}
+
+	if len(pc.sources) > 0 {
+		if _, ok := pc.sources[name]; !ok {
+			return newPolicyError(pos, node, ErrPolicySource)
+		}
+	}
+	if keys, ok := pc.keys[name]; ok {
+		if _, ok = keys[key]; !ok {
+			return newPolicyError(pos, node, ErrPolicyKey)
+		}
+	}
+	if !pc.policy.AllowStub && isQueryKey {
+		return newPolicyError(pos, node, ErrPolicyStub)
+	}
+	return
+}
+
+func (pc *cPolicyCheck) checkSourceKey(sk *SourceKey) (err error) {
+	source, key, _ := sk.srcKey()
+	return pc.checkKey(sk.Pos, sk.String(), sk.namespace(), source, key)
+}
+
+func (pc *cPolicyCheck) checkSourceRef(ref *SourceRef) (err error) {
+	if source, key, _ := ref.srcKey(); key != nil {
+		// aliases are of source keys already checked
+		err = pc.checkKey(ref.Pos, ref.String(), ref.namespace(), source, *key)
+	}
+	return
+}
+
+func (pc *cPolicyCheck) checkOperator(pos lexer.Position, node, op string) (err error) {
+	if len(pc.ops) > 0 {
+		if _, ok := pc.ops[canonicalOperator(op)]; !ok {
+			return newPolicyError(pos, node, ErrPolicyOperator)
+		}
+	}
+	return
+}
+
+func (pc *cPolicyCheck) checkValue(v *Value) (err error) {
+	if v != nil && v.SourceRef != nil {
+		err = pc.checkSourceRef(v.SourceRef)
+	}
+	return
+}
+
+func (pc *cPolicyCheck) checkExpression(e *Expression) (err error) {
+	switch {
+	case e == nil:
+	case e.Condition != nil:
+		if err = pc.checkExpression(e.Condition.Left); err != nil {
+			return
+		}
+		err = pc.checkExpression(e.Condition.Right)
+	case e.Constraint != nil:
+		c := e.Constraint
+		if err = pc.checkSourceRef(c.Left); err != nil {
+			return
+		}
+		if c.In {
+			op := "IN"
+			if c.Not {
+				op = "NOT IN"
+			}
+			if err = pc.checkOperator(c.Pos, c.String(), op); err != nil {
+				return
+			} else if pc.policy.MaxInList > 0 && len(c.Values) > pc.policy.MaxInList {
+				return newPolicyError(c.Pos, c.String(), ErrPolicyInList)
+			}
+			for _, value := range c.Values {
+				if err = pc.checkValue(value); err != nil {
+					return
+				}
+			}
+			return
+		}
+		if err = pc.checkOperator(c.Op.Pos, c.String(), c.Op.String()); err != nil {
+			return
+		}
+		err = pc.checkValue(c.Right)
+	}
+	return
+}
+
+// checkSyntax returns a copy of the syntax given with the Policy.MaxLimit
+// applied, or the PolicyError of the first violation found
+func (pc *cPolicyCheck) checkSyntax(syntax *Syntax) (checked *Syntax, err error) {
+	if syntax.Unfiltered && !pc.policy.AllowUnfiltered {
+		err = newPolicyError(syntax.Pos, "UNFILTERED", ErrPolicyUnfiltered)
+		return
+	}
+
+	if syntax.Query {
+		// QUERY statements select the query key of the primary source
+		if err = pc.checkKey(syntax.Pos, "QUERY", nil, nil, pc.eql.config.GetQueryKey()); err != nil {
+			return
+		}
+	}
+
+	for _, sk := range append(append([]*SourceKey{}, syntax.Keys...), syntax.Include...) {
+		if err = pc.checkSourceKey(sk); err != nil {
+			return
+		}
+	}
+
+	if err = pc.checkExpression(syntax.Within); err != nil {
+		return
+	}
+
+	var refs []*SourceRef
+	if syntax.GroupBy != nil {
+		refs = append(refs, *syntax.GroupBy...)
+	}
+	if syntax.OrderBy != nil {
+		if syntax.OrderBy.IsRandom() && !pc.policy.AllowRandom {
+			err = newPolicyError(syntax.OrderBy.Pos, syntax.OrderBy.String(), ErrPolicyRandom)
+			return
+		} else if syntax.OrderBy.Sources != nil {
+			refs = append(refs, *syntax.OrderBy.Sources...)
+		}
+	}
+	if syntax.Per != nil {
+		refs = append(refs, syntax.Per)
+	}
+	for _, ref := range refs {
+		if err = pc.checkSourceRef(ref); err != nil {
+			return
+		}
+	}
+
+	clone := *syntax
+	checked = &clone
+
+	if checked.Limit == nil {
+		if checked.Count && checked.GroupBy == nil {
+			// a single count
+		} else if pc.policy.RequireLimit {
+			err = newPolicyError(syntax.Pos, syntax.String(), ErrPolicyLimitRequired)
+			return
+		} else if pc.policy.MaxLimit > 0 {
+			limit := pc.policy.MaxLimit
+			checked.Limit = &limit
+		}
+	} else if pc.policy.MaxLimit > 0 && *checked.Limit > pc.policy.MaxLimit {
+		err = newPolicyError(syntax.Pos, fmt.Sprintf("LIMIT %d", *checked.Limit), ErrPolicyLimit)
+		return
+	}
+
+	if checked.Per != nil && pc.policy.MaxLimit > 0 {
+		// the LIMIT is of each PER key value, limit all of the rows too
+		limit := pc.policy.MaxLimit
+		checked.maxRows = &limit
+	}
+
+	return
+}
+
+// checkJoins plans the syntax given and checks the number of tables joined
+func (pc *cPolicyCheck) checkJoins(syntax *Syntax) (err error) {
+	if pc.policy.MaxJoins <= 0 {
+		return
+	}
+	var state *cProcessor
+	if state, err = pc.eql.prepareSyntaxBuild(syntax); err != nil {
+		return
+	} else if state.plan, err = state.preparePlan(); err != nil {
+		return
+	}
+	joins := len(state.plan.joins) + len(state.getForeignSources())
+	if state.plan.top == "" && joins > 0 {
+		// the first source of another namespace is the top table
+		joins -= 1
+	}
+	if joins > pc.policy.MaxJoins {
+		return newPolicyError(syntax.Pos, state.plan.String(), ErrPolicyJoins)
+	}
+	return
+}
+
+// checkPolicy returns a copy of the parsed Syntax with the Policy.MaxLimit
+// applied, or the PolicyError of the first violation found
+func (eql *enjinql) checkPolicy(policy *Policy, parsed *Syntax) (checked *Syntax, err error) {
+	if policy == nil {
+		err = fmt.Errorf("%w: nil policy", ErrPolicyViolation)
+		return
+	} else if parsed == nil {
+		err = fmt.Errorf("%w: nil syntax", ErrPolicyViolation)
+		return
+	}
+	pc := newPolicyCheck(eql, policy)
+	if checked, err = pc.checkSyntax(parsed); err != nil {
+		return
+	}
+	eql.m.RLock()
+	defer eql.m.RUnlock()
+	// planning a copy as QUERY statements are given their query keys
+	clone := *checked
+	if err = pc.checkJoins(&clone); err != nil {
+		checked = nil
+	}
+	return
+}
+
+// CheckPolicy returns a PolicyError for the first violation of the Policy
+// given by the parsed Syntax, which is not modified. ParseSandboxed returns
+// the Syntax with the Policy.MaxLimit applied
+func (eql *enjinql) CheckPolicy(policy *Policy, parsed *Syntax) (err error) {
+	_, err = eql.checkPolicy(policy, parsed)
+	return
+}
+
+func (eql *enjinql) ParseSandboxed(policy *Policy, format string, args ...interface{}) (parsed *Syntax, err error) {
+	var syntax *Syntax
+	if syntax, err = eql.Parse(format, args...); err != nil {
+		return
+	}
+	parsed, err = eql.checkPolicy(policy, syntax)
+	return
+}
+
+func (eql *enjinql) PerformSandboxed(policy *Policy, format string, argv ...interface{}) (columns []string, results context.Contexts, err error) {
+	if err = eql.Ready(); err == nil {
+		var parsed *Syntax
+		if parsed, err = eql.ParseSandboxed(policy, format, argv...); err != nil {
+			return
+		}
+
+		var query string
+		var args []interface{}
+		if query, args, err = eql.ParsedToSql(parsed); err != nil {
+			return
+		}
+
+		eql.m.RLock()
+		defer eql.m.RUnlock()
+
+		columns, results, err = eql.SqlQuery(query, args...)
+	}
+	return
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+)
+
+func TestPolicy(t *testing.T) {
+	Convey("query sandbox", t, func() {
+
+		eql, _ := makeQfEQL()
+
+		policy := &Policy{
+			Sources:   []string{PageSource, "word", "page_words"},
+			Keys:      map[string][]string{"word": {"word"}},
+			Operators: []string{"==", "^=", "in"},
+			MaxJoins:  2,
+			MaxInList: 2,
+			MaxLimit:  10,
+		}
+
+		_, results, err := eql.PerformSandboxed(policy, `LOOKUP .Shasum WITHIN word.Word == "quote" ORDER BY .Shasum`)
+		SoMsg("allowed error", err, ShouldBeNil)
+		SoMsg("allowed results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "0102000405"},
+			{"shasum": "1122334455"},
+		})
+
+		parsed, err := eql.ParseSandboxed(policy, `LOOKUP .Url`)
+		SoMsg("max limit applied error", err, ShouldBeNil)
+		SoMsg("max limit applied", parsed.String(), ShouldEqual, `LOOKUP .Url LIMIT 10`)
+
+		for _, test := range []struct {
+			label  string
+			input  string
+			policy *Policy
+			err    error
+			output string
+		}{
+			{"source", `LOOKUP permalink.Short`, policy, ErrPolicySource, `enjinql:1:8 policy violation: source not allowed ("permalink.Short")`},
+			{"key", `LOOKUP .Shasum WITHIN word.Flat == "quote"`, policy, ErrPolicyKey, `enjinql:1:23 policy violation: source key not allowed ("word.Flat")`},
+			{"operator", `LOOKUP .Shasum WITHIN .Url $= "quote"`, policy, ErrPolicyOperator, `enjinql:1:28 policy violation: operator not allowed (".Url $= \"quote\"")`},
+			{"negated operator", `LOOKUP .Shasum WITHIN .Url !^= "/11"`, policy, ErrPolicyOperator, `enjinql:1:28 policy violation: operator not allowed (".Url !^= \"/11\"")`},
+			{"in list", `LOOKUP .Shasum WITHIN .Type IN ("a", "b", "c")`, policy, ErrPolicyInList, `enjinql:1:23 policy violation: too many IN list values (".Type IN (\"a\", \"b\", \"c\")")`},
+			{"stub", `LOOKUP .Stub`, policy, ErrPolicyStub, `enjinql:1:8 policy violation: stub key not allowed (".Stub")`},
+			{"random", `LOOKUP .Shasum ORDER BY RANDOM()`, policy, ErrPolicyRandom, `enjinql:1:16 policy violation: ORDER BY RANDOM() not allowed ("ORDER BY RANDOM()")`},
+			{"unfiltered", `LOOKUP UNFILTERED .Shasum`, policy, ErrPolicyUnfiltered, `enjinql:1:1 policy violation: UNFILTERED not allowed ("UNFILTERED")`},
+			{"limit", `LOOKUP .Shasum LIMIT 100`, policy, ErrPolicyLimit, `enjinql:1:1 policy violation: LIMIT too large ("LIMIT 100")`},
+			{"limit required", `LOOKUP .Shasum`, &Policy{RequireLimit: true}, ErrPolicyLimitRequired, `enjinql:1:1 policy violation: LIMIT required ("LOOKUP .Shasum")`},
+			{"joins", `LOOKUP .Shasum WITHIN word.Word == "quote"`, &Policy{MaxJoins: 1}, ErrPolicyJoins, ""},
+		} {
+			_, err = eql.ParseSandboxed(test.policy, test.input)
+			SoMsg(test.label+" error", err, ShouldWrap, ErrPolicyViolation)
+			SoMsg(test.label+" specific error", err, ShouldWrap, test.err)
+			if test.output != "" {
+				SoMsg(test.label+" error message", err.Error(), ShouldEqual, test.output)
+			}
+		}
+
+		var pe *PolicyError
+		_, _, err = eql.PerformSandboxed(policy, `LOOKUP .Shasum WITHIN .Url $= "quote"`)
+		SoMsg("policy error type", errors.As(err, &pe), ShouldBeTrue)
+		SoMsg("policy error position", pe.Pos.Column, ShouldEqual, 28)
+
+		_, err = eql.ParseSandboxed(&Policy{AllowStub: true, AllowRandom: true, AllowUnfiltered: true}, `LOOKUP UNFILTERED .Stub ORDER BY RANDOM(1)`)
+		SoMsg("allowed by policy error", err, ShouldBeNil)
+
+		_, err = eql.ParseSandboxed(&Policy{}, `QUERY`)
+		SoMsg("implicit query key error", err, ShouldWrap, ErrPolicyStub)
+		_, _, err = eql.PerformSandboxed(&Policy{}, `QUERY WITHIN .Type == "quote"`)
+		SoMsg("implicit query key perform error", err, ShouldWrap, ErrPolicyStub)
+		_, results, err = eql.PerformSandboxed(&Policy{AllowStub: true}, `QUERY WITHIN .Shasum == "1122334455"`)
+		SoMsg("allowed query key error", err, ShouldBeNil)
+		SoMsg("allowed query key results", len(results), ShouldEqual, 1)
+
+		parsed, err = eql.Parse(`LOOKUP .Url`)
+		SoMsg("unchecked parse error", err, ShouldBeNil)
+		SoMsg("check policy error", eql.CheckPolicy(policy, parsed), ShouldBeNil)
+		SoMsg("check policy unmodified", parsed.Limit, ShouldBeNil)
+
+		_, results, err = eql.PerformSandboxed(&Policy{MaxLimit: 3}, `LOOKUP .Shasum, word.Word ORDER BY word.Word LIMIT 2 PER .Shasum`)
+		SoMsg("max limit per error", err, ShouldBeNil)
+		SoMsg("max limit per results", len(results), ShouldEqual, 3)
+		_, _, err = eql.PerformSandboxed(&Policy{MaxLimit: 3}, `LOOKUP .Shasum, word.Word ORDER BY word.Word LIMIT 4 PER .Shasum`)
+		SoMsg("max limit per group error", err, ShouldWrap, ErrPolicyLimit)
+
+		config, err := NewConfig("kb").
+			SetQueryKey("body").
+			NewSource("article").
+			NewStringValue("slug", 64).
+			NewStringValue("body", -1).
+			DoneSource().
+			Make()
+		SoMsg("query key config error", err, ShouldBeNil)
+		kb, tdb := makeTestEQL(config)
+		defer tdb.Close()
+		_, err = kb.ParseSandboxed(&Policy{}, `LOOKUP .Body`)
+		SoMsg("configured query key error", err, ShouldWrap, ErrPolicyStub)
+		_, err = kb.ParseSandboxed(&Policy{}, `LOOKUP .Slug`)
+		SoMsg("other key error", err, ShouldBeNil)
+
+	})
+}
@@ -16,6 +16,7 @@ package enjinql
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/iancoleman/strcase"
 
@@ -26,6 +27,7 @@ import (
 
 type cProcessSrcKey struct {
 	name string
+	n    string // namespace, when not this instance's own
 
 	k bool
 	s *cSource
@@ -39,10 +41,33 @@ type cProcessor struct {
 	builder sqlbuilder.Buildable
 	build   sqlbuilder.SelectBuilder
 	syntax  *Syntax
+	plan    *gSourcePlan
 	tables  map[string]sqlbuilder.Table
 	sources *cSources
 	order   []string
 	updated map[string]*cProcessSrcKey
+
+	// wrap is the outer query of statements selecting from an inner query
+	wrap *cWrapSelect
+
+	// namespaces returns the sources of other EnjinQL instances
+	namespaces func(prefix string) (sources *cSources, ok bool)
+}
+
+// getSources returns the sources of the namespace given, which are this
+// instance's own sources when the namespace is empty
+func (p *cProcessor) getSources(namespace string) (sources *cSources, foreign bool, err error) {
+	if namespace == "" {
+		return p.sources, false, nil
+	} else if p.namespaces != nil {
+		var ok bool
+		if sources, ok = p.namespaces(namespace); ok {
+			foreign = sources != p.sources
+			return
+		}
+	}
+	err = fmt.Errorf("%w: %q", ErrNamespaceNotFound, namespace)
+	return
 }
 
 func (p *cProcessor) findUpdatedSrcKeyRefs() (order []string, updated map[string]*cProcessSrcKey, err error) {
@@ -51,12 +76,17 @@ func (p *cProcessor) findUpdatedSrcKeyRefs() (order []string, updated map[string
 	aliased := make(map[string]*SrcKey)
 
 	stack := slices.NewStackUnique[string]()
-	primarySourceName := p.sources.getPrimarySourceName()
 
 	for _, sk := range p.syntax.Keys {
-		if sk.Source == nil || *sk.Source == "" {
-			ctxKeys[primarySourceName] = struct{}{}
-		} else if src, ok := p.sources.getSource(*sk.Source); ok {
+		sources := p.sources
+		if ns := sk.namespace(); ns != nil {
+			if sources, _, err = p.getSources(*ns); err != nil {
+				return
+			}
+		}
+		if source, _, _ := sk.srcKey(); source == nil || *source == "" {
+			ctxKeys[sources.getPrimarySourceFormal()] = struct{}{}
+		} else if src, ok := sources.getSource(*source); ok {
 			ctxKeys[src.formal()] = struct{}{}
 		}
 		if sk.Alias != nil {
@@ -74,34 +104,46 @@ func (p *cProcessor) findUpdatedSrcKeyRefs() (order []string, updated map[string
 				return
 			}
 		}
+		sources, foreign, ee := p.getSources(found.Namespace)
+		if ee != nil {
+			err = ee
+			return
+		}
 		update := &SrcKey{
 			Src: strcase.ToSnake(found.Src),
 			Key: strcase.ToSnake(found.Key),
 		}
 		if found.Src == "" {
-			update.Src = primarySourceName
+			update.Src = sources.getPrimarySourceName()
 		}
 
-		if t, ee := p.sources.T(update.Src); ee != nil {
+		if t, ee := sources.T(update.Src); ee != nil {
 			err = fmt.Errorf("%w: %q", ErrTableNotFound, found.Src)
 			return
 		} else if column := t.C(strcase.ToSnake(update.Key)); sqlbuilder.IsColumnError(column) {
 			err = fmt.Errorf("%w: %q.%q", ErrColumnNotFound, t.Name(), found.Key)
 			return
 		} else {
-			if source, ok := p.sources.getSource(update.Src); !ok {
+			if source, ok := sources.getSource(update.Src); !ok {
 				err = fmt.Errorf("unknown source name: %q", found.Src)
 				return
 			} else if columnConfig, eee := source.getColumnConfig(update.Key); eee != nil {
 				err = eee
 				return
 			} else {
+				if found.Path != "" && columnConfig.Type() != sqlbuilder.ColumnTypeString {
+					err = fmt.Errorf("%w: %q", ErrJsonPathKey, found.String())
+					return
+				}
 				update.Src = source.formal()
 				update.Key = columnConfig.Name()
 				formal := found.String()
 				_, isKey := ctxKeys[update.Src]
 				stack.Push(formal)
 				updated[formal] = &cProcessSrcKey{name: source.name, k: isKey, s: source, t: t, c: column, o: found, u: update}
+				if foreign {
+					updated[formal].n = found.Namespace
+				}
 				if found.Alias != "" {
 					updated[found.Alias] = updated[formal]
 				}
@@ -124,10 +166,14 @@ func (p *cProcessor) getRequiredSources() (required []string, err error) {
 			continue
 		}
 		unique[bsk.u.Src] = struct{}{}
+		if bsk.n != "" {
+			// other namespace sources are joined by prepareBuild
+			continue
+		}
 		required = append(required, p.sources.alias(bsk.u.Src))
 	}
 
-	if len(required) == 0 {
+	if len(required) == 0 && len(p.getForeignSources()) == 0 {
 		// there are no sources? how is this case even possible?
 		err = fmt.Errorf("no sources required, strange")
 	}
@@ -138,21 +184,127 @@ func (p *cProcessor) preparePlan() (planned *gSourcePlan, err error) {
 	var required []string
 	if required, err = p.getRequiredSources(); err != nil {
 		return
+	} else if len(required) == 0 {
+		// only other namespace sources are required
+		planned = newSourcePlan("")
+		return
 	} else if planned, err = p.sources.graph.plan(required...); err != nil {
 		return
 	}
 	return
 }
 
+// getForeignSources returns the distinct sources of other namespaces, in the
+// order they were first referenced
+func (p *cProcessor) getForeignSources() (sources []*cProcessSrcKey) {
+	unique := make(map[string]struct{})
+	for _, formal := range p.order {
+		if bsk, ok := p.updated[formal]; ok && bsk.n != "" {
+			if _, present := unique[bsk.u.Src]; !present {
+				unique[bsk.u.Src] = struct{}{}
+				sources = append(sources, bsk)
+			}
+		}
+	}
+	return
+}
+
+// findJoinConstraints returns all the WITHIN constraints which are required
+// to be true, those not within any OR conditions
+func (p *cProcessor) findJoinConstraints() (constraints []*Constraint) {
+	var walk func(e *Expression)
+	walk = func(e *Expression) {
+		switch {
+		case e == nil:
+		case e.Constraint != nil:
+			constraints = append(constraints, e.Constraint)
+		case e.Condition != nil && strings.ToUpper(e.Condition.Type) == "AND":
+			walk(e.Condition.Left)
+			walk(e.Condition.Right)
+		}
+	}
+	walk(p.syntax.Within)
+	return
+}
+
+// joinForeignSources joins the sources of other namespaces using the WITHIN
+// constraints relating each to the sources already joined
+func (p *cProcessor) joinForeignSources(top sqlbuilder.Table, joined map[string]struct{}) (table sqlbuilder.Table, err error) {
+	table = top
+	pending := p.getForeignSources()
+	constraints := p.findJoinConstraints()
+
+	if table == nil && len(pending) > 0 {
+		// only other namespace sources are required
+		table = pending[0].t
+		joined[pending[0].u.Src] = struct{}{}
+		pending = pending[1:]
+	}
+
+	for len(pending) > 0 {
+		var remaining []*cProcessSrcKey
+		for _, bsk := range pending {
+			var conditions []sqlbuilder.Condition
+			for _, constraint := range constraints {
+				if !p.isJoinConstraint(constraint, bsk.u.Src, joined) {
+					continue
+				}
+				var cond sqlbuilder.Condition
+				if cond, err = constraint.make(p); err != nil {
+					return
+				}
+				conditions = append(conditions, cond)
+			}
+			if len(conditions) == 0 {
+				remaining = append(remaining, bsk)
+				continue
+			}
+			table = table.InnerJoin(bsk.t, sqlbuilder.And(conditions...))
+			joined[bsk.u.Src] = struct{}{}
+		}
+		if len(remaining) == len(pending) {
+			err = fmt.Errorf("%w: %q", ErrNamespaceJoin, remaining[0].n+":"+remaining[0].name)
+			return
+		}
+		pending = remaining
+	}
+
+	return
+}
+
+// isJoinConstraint reports whether the constraint given relates the source
+// given to any of the sources joined, and only to those
+func (p *cProcessor) isJoinConstraint(constraint *Constraint, source string, joined map[string]struct{}) (ok bool) {
+	var this, other bool
+	for _, found := range constraint.findSources() {
+		bsk, present := p.updated[found.String()]
+		if !present {
+			return false
+		} else if bsk.u.Src == source {
+			this = true
+		} else if _, present = joined[bsk.u.Src]; present {
+			other = true
+		} else {
+			return false
+		}
+	}
+	return this && other
+}
+
 func (p *cProcessor) prepareBuild() (top sqlbuilder.Table, err error) {
 
 	var planned *gSourcePlan
 	if planned, err = p.preparePlan(); err != nil {
 		return
-	} else if source, ok := p.sources.getSource(planned.top); ok {
+	}
+
+	p.plan = planned
+	joined := make(map[string]struct{})
+	if source, ok := p.sources.getSource(planned.top); ok {
 		if top, err = source.getTable(); err != nil {
 			return
 		}
+		joined[source.formal()] = struct{}{}
 	}
 
 	for _, join := range planned.joins {
@@ -170,9 +322,120 @@ func (p *cProcessor) prepareBuild() (top sqlbuilder.Table, err error) {
 					top = top.InnerJoin(thisTable, otherColumn.Eq(thisColumn))
 				}
 			}
+			joined[source.formal()] = struct{}{}
+		}
+	}
 
+	top, err = p.joinForeignSources(top, joined)
+	return
+}
+
+// getKeySources returns the distinct sources of the selected context keys, in
+// the order they were first selected
+func (p *cProcessor) getKeySources() (sources []*cSource) {
+	unique := make(map[string]struct{})
+	for _, sk := range p.syntax.Keys {
+		if bsk, ok := p.updated[sk.lookup()]; ok {
+			if _, present := unique[bsk.u.Src]; !present {
+				unique[bsk.u.Src] = struct{}{}
+				sources = append(sources, bsk.s)
+			}
 		}
 	}
+	return
+}
+
+// getKeyColumn returns the column for the given source key, with the JSON path
+// and time bucket of the source key applied. Source keys with either and without
+// an alias are aliased with the last JSON path field name or the source key name
+func (p *cProcessor) getKeyColumn(sk *SourceKey) (column sqlbuilder.Column, alias string, ok bool) {
+	var bsk *cProcessSrcKey
+	if bsk, ok = p.updated[sk.lookup()]; ok {
+		bucket, path := sk.keyFuncs()
+		column = applyKeyFuncs(bucket, path, bsk.c)
+		if sk.Alias != nil {
+			alias = *sk.Alias
+		} else if path != nil && path.name() != "" {
+			alias = path.name()
+		} else if path != nil || bucket != nil {
+			alias = bsk.u.Key
+		}
+	}
+	return
+}
 
+// applyAlias returns the column with the JSON path and time bucket of the
+// source key with the given alias applied, if there is one
+func (p *cProcessor) applyAlias(alias string, column sqlbuilder.Column) sqlbuilder.Column {
+	for _, sk := range p.syntax.Keys {
+		if sk.Alias != nil && *sk.Alias == alias {
+			bucket, path := sk.keyFuncs()
+			return applyKeyFuncs(bucket, path, column)
+		}
+	}
+	return column
+}
+
+// applyKeyFuncs wraps the column with the JSON path and then the time bucket
+// placeholder functions, when given
+func applyKeyFuncs(bucket *string, path *JsonPath, column sqlbuilder.Column) sqlbuilder.Column {
+	if path != nil {
+		column = makeJsonPath(path, column)
+	}
+	if bucket != nil {
+		column = makeBucket(*bucket, column)
+	}
+	return column
+}
+
+// makeGroupCount returns the COUNT function for grouped statements, counting
+// the distinct key source rows when the plan fans out
+func (p *cProcessor) makeGroupCount() (fn sqlbuilder.Column, err error) {
+	sources := p.getKeySources()
+	if len(sources) == 0 {
+		err = fmt.Errorf("%w: COUNT requires at least one source key", ErrInvalidSyntax)
+		return
+	}
+	var t sqlbuilder.Table
+	if t, err = sources[0].getTable(); err != nil {
+		return
+	}
+	id := t.C(SourceIdKey)
+	if p.fansOut() {
+		fn = sqlbuilder.Func("COUNT", sqlbuilder.Func("DISTINCT", id))
+		return
+	}
+	fn = sqlbuilder.Func("COUNT", id)
+	return
+}
+
+// ordersFannedOut reports whether the ORDER BY keys include any sources
+// other than the key sources, which have more than one value for each of the
+// key source rows when the plan fans out
+func (p *cProcessor) ordersFannedOut() (fanned bool) {
+	if p.syntax.OrderBy == nil || p.syntax.OrderBy.Sources == nil {
+		return
+	}
+	sources := p.getKeySources()
+	for _, srcRef := range *p.syntax.OrderBy.Sources {
+		if bsk, ok := p.updated[srcRef.lookup()]; ok {
+			if !slices.Within(bsk.s, sources) {
+				return true
+			}
+		}
+	}
+	return
+}
+
+// fansOut reports whether the prepared plan joins any one-to-many sources
+// that are not selected, resulting in the repetition of the selected rows
+func (p *cProcessor) fansOut() (fanned bool) {
+	if p.plan != nil {
+		var names []string
+		for _, source := range p.getKeySources() {
+			names = append(names, source.name)
+		}
+		fanned = p.plan.fansOut(names...)
+	}
 	return
 }
@@ -0,0 +1,181 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-corelibs/context"
+	"github.com/go-corelibs/maps"
+	clStrings "github.com/go-corelibs/strings"
+	"github.com/go-corelibs/values"
+)
+
+var (
+	rxNamedPlaceholders = regexp.MustCompile(`\{([a-z][a-z0-9_]*)\}`)
+)
+
+// scanNamedPlaceholders returns the distinct named placeholders of the saved
+// query statement given, in the order first found outside of quoted strings
+func scanNamedPlaceholders(statement string) (names []string) {
+	unique := make(map[string]struct{})
+	scan := func(input string) {
+		for _, m := range rxNamedPlaceholders.FindAllStringSubmatch(input, -1) {
+			if _, present := unique[m[1]]; !present {
+				unique[m[1]] = struct{}{}
+				names = append(names, m[1])
+			}
+		}
+	}
+	for remainder := statement; remainder != ""; {
+		before, _, after, found := clStrings.ScanQuote(remainder)
+		scan(before)
+		if !found {
+			break
+		}
+		remainder = after
+	}
+	return
+}
+
+// prepareQuery replaces the named placeholders of the saved query statement
+// given with numbered ones and uses PrepareSyntax to format the arguments
+func prepareQuery(statement string, args map[string]interface{}) (prepared string, err error) {
+	names := scanNamedPlaceholders(statement)
+	if len(names) == 0 {
+		return statement, nil
+	}
+
+	argv := make([]interface{}, len(names))
+	numbers := make(map[string]string, len(names))
+	for idx, name := range names {
+		value, ok := args[name]
+		if !ok {
+			err = fmt.Errorf("%w: %q", ErrQueryArgument, name)
+			return
+		} else if argv[idx], err = toQueryArgument(name, value); err != nil {
+			return
+		}
+		numbers[name] = "{" + strconv.Itoa(idx+1) + "}"
+	}
+
+	var modified string
+	for remainder := statement; remainder != ""; {
+		before, quoted, after, found := clStrings.ScanQuote(remainder)
+		modified += rxNamedPlaceholders.ReplaceAllStringFunc(before, func(match string) string {
+			return numbers[match[1:len(match)-1]]
+		})
+		if !found {
+			break
+		}
+		modified += strconv.Quote(quoted)
+		remainder = after
+	}
+
+	prepared, err = PrepareSyntax(modified, argv...)
+	return
+}
+
+// toQueryArgument returns the named saved query argument given as the plain
+// scalar value formatted by PrepareSyntax, so that the values of named string
+// types are quoted the same as strings
+func toQueryArgument(name string, value interface{}) (arg interface{}, err error) {
+	value = values.ToIndirect(value)
+	if t, ok := value.(time.Time); ok {
+		return t, nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		arg = rv.String()
+	case reflect.Bool:
+		arg = rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		arg = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		arg = rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		arg = rv.Float()
+	default:
+		err = fmt.Errorf("%w: %q (%T)", ErrQueryArgumentType, name, value)
+	}
+	return
+}
+
+// initQueries validates all the saved queries against the sources, using one
+// for all the arguments
+func (eql *enjinql) initQueries() (err error) {
+	for _, name := range maps.SortedKeys(eql.config.Queries) {
+		statement := eql.config.Queries[name]
+		args := make(map[string]interface{})
+		for _, arg := range scanNamedPlaceholders(statement) {
+			args[arg] = 1
+		}
+
+		var prepared string
+		var parsed *Syntax
+		if prepared, err = prepareQuery(statement, args); err == nil {
+			if parsed, err = ParseSyntax(prepared); err == nil {
+				if parsed.Facets {
+					for _, sk := range parsed.Keys {
+						if _, err = eql.prepareFacetSQL(parsed, sk); err != nil {
+							break
+						}
+					}
+				} else {
+					_, _, err = eql.prepareSQL(parsed)
+				}
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: %q - %w", ErrInvalidQuery, name, err)
+		}
+	}
+	return
+}
+
+// prepareRun returns the saved query statement with the arguments given
+func (eql *enjinql) prepareRun(name string, args map[string]interface{}) (prepared string, err error) {
+	eql.m.RLock()
+	statement, ok := eql.config.Queries[name]
+	eql.m.RUnlock()
+	if !ok {
+		err = fmt.Errorf("%w: %q", ErrQueryNotFound, name)
+		return
+	}
+	prepared, err = prepareQuery(statement, args)
+	return
+}
+
+func (eql *enjinql) Run(name string, args map[string]interface{}) (columns []string, results context.Contexts, err error) {
+	var prepared string
+	if prepared, err = eql.prepareRun(name, args); err == nil {
+		columns, results, err = eql.Perform(prepared)
+	}
+	return
+}
+
+func (eql *enjinql) RunFacets(name string, args map[string]interface{}) (facets []*Facet, err error) {
+	var prepared string
+	if prepared, err = eql.prepareRun(name, args); err == nil {
+		facets, err = eql.PerformFacets(prepared)
+	}
+	return
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+	"github.com/go-corelibs/go-sqlbuilder/dialects"
+)
+
+type tQueryText string
+
+func TestSavedQueries(t *testing.T) {
+	Convey("saved queries", t, func() {
+
+		config, err := NewConfig("be_eql").
+			AddSource(PageSourceConfig()).
+			AddQuery("recent_by_type", `LOOKUP .url WITHIN .type == {type} ORDER BY .updated DESC LIMIT {n}`).
+			AddQuery("literal_braces", `LOOKUP .url WITHIN (.url != "{type}") AND (.type == {type})`).
+			AddQuery("by_type", `LOOKUP .url WITHIN .type == {type} ORDER BY .url`).
+			AddQuery("type_facets", `FACETS .type WITHIN .language == {language}`).
+			Make()
+		SoMsg("config error", err, ShouldBeNil)
+
+		eql, tdb := makeTestEQL(config)
+		defer tdb.Close()
+
+		march, _ := time.Parse("2006-01-02 15:04", "2024-03-17 11:25")
+		april, _ := time.Parse("2006-01-02 15:04", "2024-04-01 00:00")
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		for idx, values := range [][]interface{}{
+			{"1234567890", "en", "page", "", march, march, "/one", `["one"]`},
+			{"0123456789", "en", "page", "", april, april, "/two", `["two"]`},
+			{"9012345678", "en", "blog", "", march, march, "/three", `["three"]`},
+		} {
+			_, err = tx.TX().Insert("page", values...)
+			SoMsg(fmt.Sprintf("insert #%d error", idx), err, ShouldBeNil)
+		}
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err := eql.Run("recent_by_type", map[string]interface{}{"type": "page", "n": 1})
+		SoMsg("run error", err, ShouldBeNil)
+		SoMsg("run results", results, ShouldEqual, clContext.Contexts{
+			{"url": "/two"},
+		})
+
+		_, results, err = eql.Run("literal_braces", map[string]interface{}{"type": "blog"})
+		SoMsg("run literal braces error", err, ShouldBeNil)
+		SoMsg("run literal braces results", results, ShouldEqual, clContext.Contexts{
+			{"url": "/three"},
+		})
+
+		facets, err := eql.RunFacets("type_facets", map[string]interface{}{"language": "en"})
+		SoMsg("run facets error", err, ShouldBeNil)
+		SoMsg("run facets", facets, ShouldEqual, []*Facet{
+			{Key: ".type", Values: []*FacetValue{{Value: "page", Count: 2}, {Value: "blog", Count: 1}}},
+		})
+
+		_, results, err = eql.Run("by_type", map[string]interface{}{"type": tQueryText(`.type`)})
+		SoMsg("run named string type error", err, ShouldBeNil)
+		SoMsg("run named string type results", results, ShouldBeEmpty)
+		_, results, err = eql.Run("by_type", map[string]interface{}{"type": tQueryText(`blog" OR .type != "blog`)})
+		SoMsg("run named string type with quotes error", err, ShouldBeNil)
+		SoMsg("run named string type with quotes results", results, ShouldBeEmpty)
+		_, results, err = eql.Run("recent_by_type", map[string]interface{}{"type": tQueryText("blog"), "n": uint8(5)})
+		SoMsg("run named string type quoted error", err, ShouldBeNil)
+		SoMsg("run named string type quoted results", results, ShouldEqual, clContext.Contexts{
+			{"url": "/three"},
+		})
+		_, _, err = eql.Run("recent_by_type", map[string]interface{}{"type": []string{"page"}, "n": 1})
+		SoMsg("non-scalar argument error", err, ShouldWrap, ErrQueryArgumentType)
+		_, _, err = eql.Run("recent_by_type", map[string]interface{}{"type": nil, "n": 1})
+		SoMsg("nil argument error", err, ShouldWrap, ErrQueryArgumentType)
+
+		_, _, err = eql.Run("recent_by_type", map[string]interface{}{"type": "page"})
+		SoMsg("missing argument error", err, ShouldWrap, ErrQueryArgument)
+		_, _, err = eql.Run("nope", nil)
+		SoMsg("unknown query error", err, ShouldWrap, ErrQueryNotFound)
+
+		invalid, _ := NewConfig("be_eql").
+			AddSource(PageSourceConfig()).
+			AddQuery("unknown_source", `LOOKUP word.word WITHIN .type == {type}`).
+			Make()
+		_, err = New(invalid, tdb.DBH(), dialects.Sqlite{}, SkipCreateTable, SkipCreateIndex)
+		SoMsg("invalid query error", err, ShouldWrap, ErrInvalidQuery)
+
+	})
+}
@@ -147,6 +147,34 @@ func (c *cSource) getColumn(name string) (column sqlbuilder.Column, ok bool) {
 	return
 }
 
+// getValueType returns the value type of the named source key, which is
+// gInvalidValue for the id key and keys not found
+func (c *cSource) getValueType(name string) (ivt sourceValueType) {
+	key := strcase.ToSnake(name)
+	for _, v := range append([]cSourceValue{c.value}, c.values...) {
+		if v.key == key {
+			return v.ivt
+		}
+	}
+	return gInvalidValue
+}
+
+// getRequiredKeys returns the source keys which cannot be NULL, in column order
+func (c *cSource) getRequiredKeys() (keys []string) {
+	required := make(map[string]struct{})
+	for _, v := range append([]cSourceValue{c.value}, c.values...) {
+		if v.opt != nil && v.opt.NotNull && v.opt.Default == nil {
+			required[v.key] = struct{}{}
+		}
+	}
+	for _, key := range c.order {
+		if _, present := required[key]; present {
+			keys = append(keys, key)
+		}
+	}
+	return
+}
+
 func (c *cSource) getColumnConfig(name string) (config sqlbuilder.ColumnConfig, err error) {
 	key := strcase.ToSnake(name)
 
@@ -27,22 +27,26 @@ import (
 )
 
 type cSources struct {
-	prefix string              // snake_cased table prefix
-	order  []string            // order the sources were added
-	lookup map[string]*cSource // formal_name -> source
-	b      sqlbuilder.Buildable
+	prefix  string              // snake_cased table prefix
+	primary string              // primary source name, defaults to the first added
+	order   []string            // order the sources were added
+	lookup  map[string]*cSource // formal_name -> source
+	b       sqlbuilder.Buildable
 
 	graph *gSourceGraph
 
 	sync.RWMutex
 }
 
-func newSources(prefix string, b sqlbuilder.Buildable) *cSources {
+func newSources(prefix, primary string, b sqlbuilder.Buildable) *cSources {
+	graph := newSourceGraph()
+	graph.primary = primary
 	return &cSources{
-		prefix: strcase.ToSnake(prefix),
-		lookup: make(map[string]*cSource),
-		graph:  newSourceGraph(),
-		b:      b,
+		prefix:  strcase.ToSnake(prefix),
+		primary: primary,
+		lookup:  make(map[string]*cSource),
+		graph:   graph,
+		b:       b,
 	}
 }
 
@@ -187,6 +191,11 @@ func (c *cSources) addSource(sc *SourceConfig) (err error) {
 					value.Linked.Source, value.Linked.Key,
 					newSourceTableKey(sc.Name, value.Linked.Source+"_"+SourceIdKey),
 				)
+			} else {
+				source.node.refs = append(source.node.refs, newSourceJoin(
+					sc.Name, linkedKey,
+					newSourceTableKey(value.Linked.Source, value.Linked.Key),
+				))
 			}
 		default:
 			err = fmt.Errorf("%w: %w (%q value #%d)", ErrInvalidConfig, ErrEmptySourceValue, sc.Name, idx)
@@ -250,7 +259,9 @@ func (c *cSources) finishAddSource(source *cSource) (err error) {
 func (c *cSources) getPrimarySource() (source *cSource, ok bool) {
 	c.RLock()
 	defer c.RUnlock()
-	if ok = len(c.order) > 0; ok {
+	if c.primary != "" {
+		source, ok = c.lookup[c.primary]
+	} else if ok = len(c.order) > 0; ok {
 		//source, ok = c.lookup[c.formal(c.order[0])]
 		source, ok = c.lookup[c.order[0]]
 	}
@@ -0,0 +1,54 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPrimarySource(t *testing.T) {
+	Convey("designated primary source", t, func() {
+
+		config, err := NewConfig("be_eql").
+			SetPrimary(PageSource).
+			NewSource("word").NewStringValue("word", 64).DoneSource().
+			AddSource(PageSourceConfig()).
+			NewSource("page_words").
+			SetParent(PageSource).
+			NewLinkedValue("word", SourceIdKey).
+			DoneSource().
+			Make()
+		SoMsg("config error", err, ShouldBeNil)
+		SoMsg("config primary", config.GetPrimary(), ShouldEqual, PageSource)
+		SoMsg("default primary", NewConfig().NewSource("word").NewStringValue("word", 64).DoneSource().GetPrimary(), ShouldEqual, "word")
+
+		eql, tdb := makeTestEQL(config)
+		defer tdb.Close()
+
+		query, _, err := eql.ToSQL(`LOOKUP .Shasum WITHIN word.Word == "quote"`)
+		SoMsg("primary lookup error", err, ShouldBeNil)
+		SoMsg("primary lookup query", query, ShouldContainSubstring, `SELECT DISTINCT "be_eql_page"."shasum" AS "eql_row_0", "be_eql_page"."id" AS "eql_row_1" FROM "be_eql_page" INNER JOIN`)
+
+		query, _, err = eql.ToSQL(`QUERY`)
+		SoMsg("primary query error", err, ShouldBeNil)
+		SoMsg("primary query query", query, ShouldEqual, `SELECT "be_eql_page"."stub" FROM "be_eql_page";`)
+
+		_, err = NewConfig("be_eql").SetPrimary("nope").AddSource(PageSourceConfig()).Make()
+		SoMsg("primary not found error", err, ShouldWrap, ErrPrimaryNotFound)
+
+	})
+}
@@ -342,16 +342,22 @@ func TestEnjinQL(t *testing.T) {
 	})
 }
 
-func makeBeEQL() (eql EnjinQL, dbh testdb.TestDB) {
+// makeTestEQL returns a new EnjinQL instance for the config and options
+// given, backed by a new sqlite3 test database
+func makeTestEQL(config *Config, options ...Option) (eql EnjinQL, dbh testdb.TestDB) {
 	var err error
-	tmpfile := tdata.TempFile("", "enjinql.*.be.db")
 	if dbh, err = testdb.NewTestDB(); err != nil {
-		panic(fmt.Errorf("%s: %v", tmpfile, err))
+		panic(err)
 	}
-	config := makeBeConfig()
-	if eql, err = New(config, dbh.DBH(), dialects.Sqlite{}); err != nil {
-		panic(fmt.Errorf("%s: %v", tmpfile, err))
+	if eql, err = New(config, dbh.DBH(), dialects.Sqlite{}, options...); err != nil {
+		dbh.Close()
+		panic(err)
 	}
+	return
+}
+
+func makeBeEQL() (eql EnjinQL, dbh testdb.TestDB) {
+	eql, dbh = makeTestEQL(makeBeConfig())
 	makeBeData(eql)
 	return
 }
@@ -409,14 +415,7 @@ func hasWord(eql EnjinQL, word string) (id int64) {
 }
 
 func makeQfEQL() (eql EnjinQL, dbh testdb.TestDB) {
-	var err error
-	tmpfile := tdata.TempFile("", "enjinql.*.qf.db")
-	if dbh, err = testdb.NewTestDB(); err != nil {
-		panic(fmt.Errorf("%s: %v", tmpfile, err))
-	}
-	if eql, err = New(makeQfConfig(), dbh.DBH(), dialects.Sqlite{}); err != nil {
-		panic(err)
-	}
+	eql, dbh = makeTestEQL(makeQfConfig())
 	makeBeData(eql)
 	makeQfQuote(eql, "1122334455", "/1122334455aabbccddeeff", "this is the contents of a quote")
 	makeQfQuote(eql, "0102000405", "/0102030405aabbccddeeff", "and this is the body of another quote")
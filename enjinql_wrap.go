@@ -0,0 +1,168 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+)
+
+const (
+	// RowKeyPrefix is the column alias prefix used for the values selected by
+	// the inner query of statements wrapped by an outer query
+	RowKeyPrefix = "eql_row_"
+)
+
+// makeRowKey returns the column alias for the inner query value at idx
+func makeRowKey(idx int) string {
+	return RowKeyPrefix + strconv.Itoa(idx)
+}
+
+type cWrapKey struct {
+	key  string
+	name string
+}
+
+type cWrapOrder struct {
+	key  string
+	desc bool
+}
+
+// cWrapSelect is the outer query of statements selecting from the rows of an
+// inner query. Statements joining one-to-many sources select the DISTINCT
+// key values and key source ids within the inner query, and the outer query
+// orders and limits the rows remaining. Statements using LIMIT n PER <key>
+// select the PER key within the inner query and the outer query limits the
+// rows of each PER key value
+//
+// Statements ordered by the keys of the sources repeating the selected rows
+// cannot select those rows DISTINCT, the inner query groups the rows by the
+// key source ids instead and orders by the MIN, or MAX when descending, of
+// the ORDER BY values of each group
+//
+// The inner query values are all aliased with RowKeyPrefix, so that keys of
+// the same name from different sources are not ambiguous, and the outer query
+// selects each key by the name it would have had without the wrapping
+type cWrapSelect struct {
+	keys    []cWrapKey
+	passed  []string
+	columns []sqlbuilder.Column
+	order   []cWrapOrder
+	random  bool
+	grouped bool
+	count   int
+
+	// per is the inner query alias of the PER key, see makePer
+	per string
+}
+
+// next returns the alias for the next inner query value
+func (w *cWrapSelect) next() (key string) {
+	key = makeRowKey(w.count)
+	w.count += 1
+	return
+}
+
+// addKey returns the key column given aliased for the inner query, the outer
+// query selects it as the name given
+func (w *cWrapSelect) addKey(column sqlbuilder.Column, name string) sqlbuilder.Column {
+	key := w.next()
+	w.keys = append(w.keys, cWrapKey{key: key, name: name})
+	return column.As(key)
+}
+
+// pass selects the inner query value given as-is within the outer query
+func (w *cWrapSelect) pass(key string) {
+	w.passed = append(w.passed, key)
+}
+
+// add selects the column given within the inner query only, returning its
+// alias
+func (w *cWrapSelect) add(column sqlbuilder.Column) (key string) {
+	key = w.next()
+	w.columns = append(w.columns, column.As(key))
+	return
+}
+
+// orderBy selects the columns given within the inner query and orders the
+// outer query by them
+func (w *cWrapSelect) orderBy(desc bool, columns ...sqlbuilder.Column) {
+	for _, column := range columns {
+		if w.grouped {
+			if desc {
+				column = sqlbuilder.Func("MAX", column)
+			} else {
+				column = sqlbuilder.Func("MIN", column)
+			}
+		}
+		w.order = append(w.order, cWrapOrder{key: w.add(column), desc: desc})
+	}
+}
+
+// make returns the outer query selecting from the inner query given
+func (w *cWrapSelect) make(d sqlbuilder.Dialect, inner string, limit, offset *int) (query string) {
+	inner = strings.TrimSuffix(inner, d.QuerySuffix())
+
+	from := d.QuoteField(RowKeyPrefix + "a")
+	quote := func(key string) string {
+		return from + "." + d.QuoteField(key)
+	}
+
+	var selected []string
+	for _, wk := range w.keys {
+		selected = append(selected, quote(wk.key)+" AS "+d.QuoteField(wk.name))
+	}
+	for _, key := range w.passed {
+		selected = append(selected, quote(key))
+	}
+
+	var ordered []string
+	for _, wo := range w.order {
+		if wo.desc {
+			ordered = append(ordered, quote(wo.key)+" DESC")
+		} else {
+			ordered = append(ordered, quote(wo.key)+" ASC")
+		}
+	}
+	if w.random {
+		ordered = append(ordered, getRandomFunc(d)+"()")
+	}
+
+	query = "SELECT " + strings.Join(selected, ", ")
+	query += " FROM ( " + inner + " ) AS " + from
+	if len(ordered) > 0 {
+		query += " ORDER BY " + strings.Join(ordered, ", ")
+	}
+	if limit != nil {
+		query += " LIMIT " + strconv.Itoa(*limit)
+	}
+	if offset != nil {
+		query += " OFFSET " + strconv.Itoa(*offset)
+	}
+	query += d.QuerySuffix()
+	return
+}
+
+// orderBy orders the statement by the columns given, wrapped statements are
+// ordered by the outer query
+func (p *cProcessor) orderBy(desc bool, columns ...sqlbuilder.Column) {
+	if p.wrap != nil {
+		p.wrap.orderBy(desc, columns...)
+		return
+	}
+	p.build.OrderBy(desc, columns...)
+}
@@ -28,9 +28,76 @@ var (
 	ErrMismatchQuery  = errors.New("QUERY does not return keyed values; use LOOKUP for context specifics")
 	ErrMismatchLookup = errors.New("LOOKUP does not return entire pages; use QUERY for complete pages")
 
+	ErrMismatchQueryCount = errors.New("QUERY does not support COUNT or DISTINCT; use LOOKUP for context specifics")
+	ErrAllDistinct        = errors.New("ALL and DISTINCT are mutually exclusive")
+
+	ErrIncludeQuery = errors.New("INCLUDE requires a QUERY statement; use LOOKUP keys otherwise")
+
+	ErrMismatchFacets  = errors.New("FACETS requires at least one source key")
+	ErrFacetsClauses   = errors.New("FACETS does not support ALL, COUNT, DISTINCT, GROUP BY, ORDER BY, AFTER, OFFSET or PER")
+	ErrFacetsStatement = errors.New("FACETS statements are performed with PerformFacets")
+	ErrFacetCount      = errors.New("FACETS count is not an integer")
+
+	ErrAfterValue     = errors.New("AFTER requires a string cursor value")
+	ErrAfterCount     = errors.New("AFTER does not support COUNT or DISTINCT")
+	ErrAfterRandom    = errors.New("AFTER requires ORDER BY RANDOM() to have a seed")
+	ErrInvalidCursor  = errors.New("invalid cursor")
 	ErrNegativeOffset = errors.New("negative offset")
 	ErrNegativeLimit  = errors.New("negative limit")
 
+	ErrPerLimit   = errors.New("PER requires a LIMIT")
+	ErrPerClauses = errors.New("PER does not support COUNT, DISTINCT, AFTER or OFFSET")
+	ErrPerRandom  = errors.New("PER requires ORDER BY RANDOM() to have a seed")
+
+	ErrOrderByFanOut = errors.New("ORDER BY keys of sources repeating the selected rows require ALL with AFTER or PER")
+
+	ErrRandomDialect = errors.New("seeded RANDOM() is not supported by the dialect")
+
+	ErrBucketDialect = errors.New("time buckets are not supported by the dialect")
+
+	ErrJsonPath    = errors.New("JSON paths are limited to field names and array indexes, for example: $.field[0]")
+	ErrJsonPathKey = errors.New("JSON paths require a string source key")
+	ErrJsonDialect = errors.New("JSON paths are not supported by the dialect")
+
+	ErrGroupByLookup  = errors.New("GROUP BY requires a LOOKUP statement")
+	ErrGroupByClauses = errors.New("GROUP BY does not support ALL, DISTINCT, AFTER or PER")
+
+	ErrNamespacePrefix   = errors.New("namespaces require a non-empty config prefix")
+	ErrNamespaceExists   = errors.New("namespace exists")
+	ErrNamespaceNotFound = errors.New("namespace not found")
+	ErrNamespaceJoin     = errors.New("namespace sources require a WITHIN constraint relating them to the other sources")
+	ErrDropTable         = errors.New("error dropping table")
+
+	ErrInvalidFilter = errors.New("invalid filter expression")
+
+	ErrInvalidQuery      = errors.New("invalid saved query")
+	ErrQueryNotFound     = errors.New("saved query not found")
+	ErrQueryArgument     = errors.New("missing saved query argument")
+	ErrQueryArgumentType = errors.New("saved query arguments must be strings, numbers, booleans or times")
+
+	ErrPolicyViolation     = errors.New("policy violation")
+	ErrPolicySource        = errors.New("source not allowed")
+	ErrPolicyKey           = errors.New("source key not allowed")
+	ErrPolicyOperator      = errors.New("operator not allowed")
+	ErrPolicyNamespace     = errors.New("other namespaces not allowed")
+	ErrPolicyStub          = errors.New("stub key not allowed")
+	ErrPolicyRandom        = errors.New("ORDER BY RANDOM() not allowed")
+	ErrPolicyUnfiltered    = errors.New("UNFILTERED not allowed")
+	ErrPolicyInList        = errors.New("too many IN list values")
+	ErrPolicyJoins         = errors.New("too many joins")
+	ErrPolicyLimit         = errors.New("LIMIT too large")
+	ErrPolicyLimitRequired = errors.New("LIMIT required")
+
+	ErrInsertValues    = errors.New("INSERT requires one value for each source key")
+	ErrModifyValue     = errors.New("INSERT and UPDATE values cannot be source keys")
+	ErrModifyWithin    = errors.New("UPDATE and DELETE require a WITHIN expression")
+	ErrModifyStatement = errors.New("INSERT, UPDATE and DELETE statements are performed with SqlTX.Execute")
+	ErrSelectStatement = errors.New("LOOKUP, QUERY and FACETS statements are performed with Perform")
+	ErrUpdateRows      = errors.New("update rows error")
+
+	ErrPaginateCount = errors.New("COUNT statements cannot be paginated")
+	ErrPerPage       = errors.New("per-page must be greater than zero")
+
 	ErrMissingSourceKey = errors.New("missing source key")
 	ErrMissingOperator  = errors.New("missing operator")
 	ErrMissingLeftSide  = errors.New("missing left-hand side expression")
@@ -50,6 +117,7 @@ var (
 	ErrNoSources            = errors.New("at least one source is required")
 	ErrNoSourceValues       = errors.New("at least one source value is required")
 	ErrParentNotFound       = errors.New("parent not found")
+	ErrPrimaryNotFound      = errors.New("primary source not found")
 	ErrNotSnakeCased        = errors.New("all names and keys must be snake_cased")
 	ErrUnnamedSource        = errors.New("unnamed source")
 	ErrEmptySourceValue     = errors.New("empty source value")
@@ -61,13 +129,39 @@ var (
 	ErrCreateTableSQL       = errors.New("error building create table sql")
 	ErrCreateTable          = errors.New("error creating table sql")
 
-	ErrQueryRequiresStub = errors.New("eql query statements require a \"stub\" column")
+	ErrQueryRequiresStub = errors.New("eql query statements require the primary source to have the query key column")
 
 	ErrDeleteRows    = errors.New("delete rows error")
 	ErrInsertRow     = errors.New("insert row error")
 	ErrTooManyValues = errors.New("too many values given")
 	ErrNoValues      = errors.New("at least the first column value is required")
 	ErrInvalidID     = errors.New("row identifiers must be greater than zero")
+	ErrMissingKey    = errors.New("missing required source key")
+	ErrDuplicateKey  = errors.New("duplicate source key")
+	ErrInsertStruct  = errors.New("InsertStruct requires a struct or a pointer to a struct")
+
+	ErrNoUniqueKeys    = errors.New("source has no unique constraints")
+	ErrUniqueValues    = errors.New("values are required for all the keys of at least one unique constraint")
+	ErrConflictDialect = errors.New("insert conflicts are not supported by the dialect")
+
+	ErrBulkValues    = errors.New("bulk insert rows require the same number of values")
+	ErrBulkClosed    = errors.New("bulk inserter is closed")
+	ErrBulkValueType = errors.New("bulk insert value type not accepted by the column")
+	ErrBulkDropped   = errors.New("bulk insert rows dropped")
+
+	ErrCascadeCycle = errors.New("circular source references cannot be deleted in cascade")
+
+	ErrGarbageSource = errors.New("garbage is only collected from data sources referenced by linked values")
+
+	ErrSyncChildren   = errors.New("sync children error")
+	ErrNotChildSource = errors.New("source is not a child of the parent source")
+
+	ErrResolveID   = errors.New("resolve id error")
+	ErrIDNotFound  = errors.New("no row found with the natural key value")
+	ErrAmbiguousID = errors.New("more than one row found with the natural key value")
+
+	ErrSavepoint     = errors.New("savepoint error")
+	ErrSavepointName = errors.New("savepoint names must be plain SQL identifiers")
 
 	ErrUnmarshalEnjinQL = errors.New("use enjinql.ParseConfig and enjinql.New to restore an EnjinQL instance")
 )
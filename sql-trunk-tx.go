@@ -16,6 +16,13 @@ package enjinql
 
 import (
 	"database/sql"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+)
+
+var (
+	rxSavepointName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 )
 
 var _ SqlTrunkTX = (*cSqlTrunkTX)(nil)
@@ -28,18 +35,48 @@ type SqlTrunkTX interface {
 	Valid() bool
 	Commit() (err error)
 	Rollback() (err error)
+
+	// Savepoint marks the current state of the transaction with the name
+	// given, which must be a plain SQL identifier
+	Savepoint(name string) (err error)
+	// RollbackTo undoes all the work done since the named Savepoint, which
+	// remains available to be rolled back to again
+	RollbackTo(name string) (err error)
+	// Release removes the named Savepoint, keeping all the work done since
+	Release(name string) (err error)
+
+	// Nested starts a new Savepoint and returns a SqlTrunkTX for it, where
+	// Commit releases the savepoint and Rollback undoes only the work done
+	// with the nested SqlTrunkTX, for example:
+	//
+	//	for _, page := range pages {
+	//		nested, _ := tx.Nested()
+	//		if err := indexPage(nested, page); err != nil {
+	//			_ = nested.Rollback() // skip this page only
+	//			continue
+	//		}
+	//		_ = nested.Commit()
+	//	}
+	Nested() (nested SqlTrunkTX, err error)
 }
 
 type cSqlTrunkTX struct {
 	cSqlTX
+
+	// savepoint is the name of the Nested savepoint, empty for the trunk
+	savepoint string
+	// counter is shared by all Nested savepoints of the trunk
+	counter *atomic.Int64
 }
 
 func newSqlTrunkTX(tx *sql.Tx, eql *enjinql) *cSqlTrunkTX {
 	return &cSqlTrunkTX{
-		cSqlTX{
+		cSqlTX: cSqlTX{
 			tx:  tx,
 			eql: eql,
+			ids: newResolveCache(gResolveCacheSize),
 		},
+		counter: &atomic.Int64{},
 	}
 }
 
@@ -47,6 +84,7 @@ func (c *cSqlTrunkTX) TX() SqlTX {
 	return &cSqlTX{
 		tx:  c.tx,
 		eql: c.eql,
+		ids: c.ids,
 	}
 }
 
@@ -56,7 +94,12 @@ func (c *cSqlTrunkTX) Valid() bool {
 
 func (c *cSqlTrunkTX) Commit() (err error) {
 	if c.Valid() {
-		if err = c.tx.Commit(); err == nil {
+		if c.savepoint != "" {
+			err = c.Release(c.savepoint)
+		} else {
+			err = c.tx.Commit()
+		}
+		if err == nil {
 			c.tx = nil
 		}
 	}
@@ -65,9 +108,63 @@ func (c *cSqlTrunkTX) Commit() (err error) {
 
 func (c *cSqlTrunkTX) Rollback() (err error) {
 	if c.Valid() {
-		if err = c.tx.Rollback(); err == nil {
+		if c.savepoint != "" {
+			if err = c.RollbackTo(c.savepoint); err == nil {
+				err = c.Release(c.savepoint)
+			}
+		} else {
+			err = c.tx.Rollback()
+		}
+		if err == nil {
 			c.tx = nil
 		}
 	}
 	return
 }
+
+// execSavepoint performs the savepoint statement given for the named savepoint
+func (c *cSqlTrunkTX) execSavepoint(statement, name string) (err error) {
+	if !c.Valid() {
+		return fmt.Errorf("%w: %w", ErrSavepoint, sql.ErrTxDone)
+	} else if !rxSavepointName.MatchString(name) {
+		return fmt.Errorf("%w: %w: %q", ErrSavepoint, ErrSavepointName, name)
+	}
+	query := statement + " " + c.eql.dialect.QuoteField(name) + c.eql.dialect.QuerySuffix()
+	if _, err = c.tx.Exec(query); err != nil {
+		err = fmt.Errorf("%w: %w", ErrSavepoint, err)
+	}
+	return
+}
+
+func (c *cSqlTrunkTX) Savepoint(name string) (err error) {
+	return c.execSavepoint("SAVEPOINT", name)
+}
+
+func (c *cSqlTrunkTX) RollbackTo(name string) (err error) {
+	if err = c.execSavepoint("ROLLBACK TO SAVEPOINT", name); err == nil {
+		// ids resolved since the savepoint may no longer exist
+		c.ids.forget()
+	}
+	return
+}
+
+func (c *cSqlTrunkTX) Release(name string) (err error) {
+	return c.execSavepoint("RELEASE SAVEPOINT", name)
+}
+
+func (c *cSqlTrunkTX) Nested() (nested SqlTrunkTX, err error) {
+	name := fmt.Sprintf("eql_nested_%d", c.counter.Add(1))
+	if err = c.Savepoint(name); err != nil {
+		return
+	}
+	nested = &cSqlTrunkTX{
+		cSqlTX: cSqlTX{
+			tx:  c.tx,
+			eql: c.eql,
+			ids: c.ids,
+		},
+		savepoint: name,
+		counter:   c.counter,
+	}
+	return
+}
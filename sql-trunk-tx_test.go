@@ -0,0 +1,85 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSavepoints(t *testing.T) {
+	Convey("Savepoints and Nested", t, func() {
+
+		eql, dbh := makeQfEQL()
+		defer dbh.Close()
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		defer tx.Rollback()
+
+		present := func(word string) bool {
+			var count int
+			ee := tx.QueryRow(`SELECT COUNT(*) FROM qf_eql_word WHERE word = ?`, word).Scan(&count)
+			SoMsg("present query error", ee, ShouldBeNil)
+			return count > 0
+		}
+		insert := func(tx SqlTX, word string) {
+			_, ee := tx.Insert("word", word[:1], word, word)
+			SoMsg("insert word error", ee, ShouldBeNil)
+		}
+
+		insert(tx, "kept")
+		SoMsg("savepoint error", tx.Savepoint("before"), ShouldBeNil)
+		insert(tx, "undone")
+		SoMsg("undone present", present("undone"), ShouldBeTrue)
+		SoMsg("rollback to error", tx.RollbackTo("before"), ShouldBeNil)
+		SoMsg("undone rolled back", present("undone"), ShouldBeFalse)
+		SoMsg("kept present", present("kept"), ShouldBeTrue)
+		SoMsg("release error", tx.Release("before"), ShouldBeNil)
+		SoMsg("release again", tx.Release("before"), ShouldWrap, ErrSavepoint)
+		SoMsg("bad savepoint name", tx.Savepoint("bad name"), ShouldWrap, ErrSavepointName)
+
+		skipped, err := tx.Nested()
+		SoMsg("nested skipped error", err, ShouldBeNil)
+		insert(skipped, "skipped")
+		SoMsg("nested skipped rollback", skipped.Rollback(), ShouldBeNil)
+		SoMsg("nested skipped valid", skipped.Valid(), ShouldBeFalse)
+		SoMsg("skipped rolled back", present("skipped"), ShouldBeFalse)
+
+		indexed, err := tx.Nested()
+		SoMsg("nested indexed error", err, ShouldBeNil)
+		insert(indexed, "indexed")
+		SoMsg("nested indexed commit", indexed.Commit(), ShouldBeNil)
+		SoMsg("indexed present", present("indexed"), ShouldBeTrue)
+
+		outer, err := tx.Nested()
+		SoMsg("nested outer error", err, ShouldBeNil)
+		inner, err := outer.Nested()
+		SoMsg("nested inner error", err, ShouldBeNil)
+		insert(inner.TX(), "inner")
+		SoMsg("nested inner commit", inner.Commit(), ShouldBeNil)
+		SoMsg("inner present", present("inner"), ShouldBeTrue)
+		SoMsg("nested outer rollback", outer.Rollback(), ShouldBeNil)
+		SoMsg("inner rolled back", present("inner"), ShouldBeFalse)
+
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+		SoMsg("savepoint after commit", tx.Savepoint("after"), ShouldWrap, ErrSavepoint)
+		SoMsg("kept committed", hasWord(eql, "kept"), ShouldBeGreaterThan, 0)
+		SoMsg("indexed committed", hasWord(eql, "indexed"), ShouldBeGreaterThan, 0)
+		SoMsg("skipped not committed", hasWord(eql, "skipped"), ShouldEqual, 0)
+
+	})
+}
@@ -0,0 +1,416 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+	"github.com/go-corelibs/values"
+)
+
+var (
+	// gBulkParameterLimits are the maximum number of bind variables of a
+	// single statement for each dialect, see getBulkParameterLimit for the
+	// sqlite3 versions supporting more
+	gBulkParameterLimits = map[string]int{
+		"sqlite3":    999,
+		"postgresql": 65535,
+		"mysql":      65535,
+	}
+)
+
+const (
+	// gBulkSqliteParameterLimit is the bind variable limit of SQLite 3.32.0
+	// and later
+	gBulkSqliteParameterLimit = 32766
+	// gBulkDefaultParameterLimit is the bind variable limit of dialects not
+	// present in gBulkParameterLimits
+	gBulkDefaultParameterLimit = 999
+)
+
+// BulkInserter batches the rows added into multi-row INSERT statements, see
+// SqlTX.BulkInsert
+type BulkInserter interface {
+	// Add appends a row of values, in the same positional order as
+	// SqlTX.Insert, flushing the pending rows when the batch is full. All rows
+	// must have the same number of values as the first
+	Add(values ...interface{}) (err error)
+	// Flush inserts all pending rows. When the batch fails to insert, its rows
+	// are inserted one at a time and the error returned lists the numbers of
+	// the rows dropped, counting all the calls to Add from one
+	Flush() (err error)
+	// Close flushes any pending rows and closes the prepared statements, the
+	// BulkInserter cannot be used after closing
+	Close() (err error)
+	// Inserted returns the number of rows inserted so far
+	Inserted() (count int64)
+	// BatchSize returns the number of rows of each full batch
+	BatchSize() (rows int)
+}
+
+// BulkProgress is called after each batch is inserted with the ids of the
+// rows of the batch inserted, in the order added, and the number of rows
+// inserted so far. The ids are nil for dialects not reporting them
+type BulkProgress func(ids []int64, inserted int64)
+
+// BulkOption configures a BulkInserter
+type BulkOption func(b *cBulkInserter)
+
+// BulkBatchSize limits the number of rows of each batch, the dialect's bind
+// variable limit applies when zero or when the size given exceeds it
+func BulkBatchSize(rows int) BulkOption {
+	return func(b *cBulkInserter) {
+		b.size = rows
+	}
+}
+
+// BulkOnProgress sets the BulkProgress func called after each batch
+func BulkOnProgress(fn BulkProgress) BulkOption {
+	return func(b *cBulkInserter) {
+		b.progress = fn
+	}
+}
+
+type cBulkInserter struct {
+	tx       *cSqlTX
+	source   *cSource
+	table    sqlbuilder.Table
+	columns  []sqlbuilder.Column
+	configs  []sqlbuilder.ColumnConfig
+	limit    int
+	size     int
+	prefix   string
+	pending  []interface{}
+	rows     int
+	numbers  []int64
+	added    int64
+	inserted int64
+	stmts    map[int]*sql.Stmt
+	progress BulkProgress
+	closed   bool
+}
+
+func (c *cSqlTX) BulkInsert(name string, options ...BulkOption) (bulk BulkInserter, err error) {
+	source, ok := c.getSource(name)
+	if !ok {
+		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+		return
+	}
+	b := &cBulkInserter{
+		tx:     c,
+		source: source,
+		stmts:  make(map[int]*sql.Stmt),
+	}
+	if b.table, err = source.getTable(); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, err)
+		return
+	}
+	for _, option := range options {
+		option(b)
+	}
+	b.limit = c.getBulkParameterLimit()
+	bulk = b
+	return
+}
+
+// getBulkParameterLimit returns the bind variable limit of the dialect
+func (c *cSqlTX) getBulkParameterLimit() (limit int) {
+	name := c.eql.dialect.Name()
+	var ok bool
+	if limit, ok = gBulkParameterLimits[name]; !ok {
+		return gBulkDefaultParameterLimit
+	} else if name == "sqlite3" {
+		var version string
+		if err := c.tx.QueryRow(`SELECT sqlite_version()`).Scan(&version); err == nil {
+			parts := strings.SplitN(version, ".", 3)
+			if len(parts) >= 2 {
+				major, _ := strconv.Atoi(parts[0])
+				minor, _ := strconv.Atoi(parts[1])
+				if major > 3 || (major == 3 && minor >= 32) {
+					limit = gBulkSqliteParameterLimit
+				}
+			}
+		}
+	}
+	return
+}
+
+func (b *cBulkInserter) BatchSize() (rows int) {
+	if len(b.columns) == 0 {
+		// unknown until the first row is added
+		return b.size
+	}
+	rows = b.limit / len(b.columns)
+	if b.size > 0 && b.size < rows {
+		rows = b.size
+	}
+	return
+}
+
+func (b *cBulkInserter) Inserted() (count int64) {
+	return b.inserted
+}
+
+func (b *cBulkInserter) Add(values ...interface{}) (err error) {
+	if b.closed {
+		return fmt.Errorf("%w: %w", ErrInsertRow, ErrBulkClosed)
+	}
+	b.added += 1
+
+	if b.columns == nil {
+		var columns []sqlbuilder.Column
+		if _, columns, err = b.tx.prepareInsertColumns(b.source.name, values); err != nil {
+			return
+		}
+		names := make([]string, len(columns))
+		configs := make([]sqlbuilder.ColumnConfig, len(columns))
+		for idx, key := range b.source.order[:len(columns)] {
+			if configs[idx], err = b.source.getColumnConfig(key); err != nil {
+				return fmt.Errorf("%w: %w", ErrInsertRow, err)
+			}
+			names[idx] = b.tx.eql.dialect.QuoteField(key)
+		}
+		b.columns, b.configs = columns, configs
+		b.prefix = "INSERT INTO " + b.tx.eql.dialect.QuoteField(b.table.Name()) + " ( " + strings.Join(names, ", ") + " )"
+	} else if len(values) != len(b.columns) {
+		return fmt.Errorf("%w: %w: %d of %d (row #%d)", ErrInsertRow, ErrBulkValues, len(values), len(b.columns), b.added)
+	}
+
+	argv := make([]interface{}, len(values))
+	for idx, value := range values {
+		if argv[idx], err = convertBulkValue(b.configs[idx], value); err != nil {
+			return fmt.Errorf("%w: %w (row #%d)", ErrInsertRow, err, b.added)
+		}
+	}
+
+	b.pending = append(b.pending, argv...)
+	b.numbers = append(b.numbers, b.added)
+	if b.rows += 1; b.rows >= b.BatchSize() {
+		err = b.Flush()
+	}
+	return
+}
+
+// convertBulkValue validates the value against the column config and returns
+// the driver value bound for it, the same as the single row INSERT builder
+func convertBulkValue(config sqlbuilder.ColumnConfig, value interface{}) (converted interface{}, err error) {
+	value = values.ToIndirect(value)
+	if value == nil {
+		if opt := config.Option(); opt != nil && opt.NotNull {
+			err = fmt.Errorf("%w: %q", ErrBulkValueType, config.Name())
+		}
+		return
+	}
+
+	if _, ok := value.(driver.Valuer); !ok && config.Type() != sqlbuilder.ColumnTypeAny {
+		var accepted bool
+		vt := reflect.TypeOf(value)
+		for _, t := range config.Type().CapableTypes() {
+			if accepted = t == vt; accepted {
+				break
+			}
+		}
+		if !accepted {
+			err = fmt.Errorf("%w: %q does not accept %T", ErrBulkValueType, config.Name(), value)
+			return
+		}
+	}
+
+	switch t := value.(type) {
+	case int, int8, int16, int32, int64:
+		converted = reflect.ValueOf(t).Int()
+	case uint, uint8, uint16, uint32, uint64:
+		converted = int64(reflect.ValueOf(t).Uint())
+	case float32, float64:
+		converted = reflect.ValueOf(t).Float()
+	case bool, []byte, string, time.Time, driver.Valuer:
+		converted = t
+	default:
+		err = fmt.Errorf("%w: %q does not accept %T", ErrBulkValueType, config.Name(), value)
+	}
+	return
+}
+
+// getStatement returns the prepared statement for the number of rows given
+func (b *cBulkInserter) getStatement(rows int) (stmt *sql.Stmt, err error) {
+	if stmt = b.stmts[rows]; stmt != nil {
+		return
+	}
+
+	dialect := b.tx.eql.dialect
+	numColumns := len(b.columns)
+	var buf strings.Builder
+	buf.WriteString(b.prefix)
+	buf.WriteString(" VALUES ")
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString("( ")
+		for col := 0; col < numColumns; col++ {
+			if col > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(dialect.BindVar(row*numColumns + col + 1))
+		}
+		buf.WriteString(" )")
+	}
+	if dialect.Name() == "postgresql" {
+		buf.WriteString(" RETURNING " + dialect.QuoteField(SourceIdKey))
+	}
+	buf.WriteString(dialect.QuerySuffix())
+
+	if stmt, err = b.tx.tx.Prepare(buf.String()); err == nil {
+		b.stmts[rows] = stmt
+	}
+	return
+}
+
+func (b *cBulkInserter) Flush() (err error) {
+	if b.rows == 0 {
+		return
+	}
+	defer func() {
+		b.pending = b.pending[:0]
+		b.numbers = b.numbers[:0]
+		b.rows = 0
+	}()
+
+	// each statement is within a savepoint because postgres cannot continue
+	// the transaction after an error
+	var ids []int64
+	var inserted int
+	var dropped []int64
+	if err = b.savepoint("SAVEPOINT"); err != nil {
+		return fmt.Errorf("%w: %w", ErrInsertRow, err)
+	} else if ids, err = b.insertRows(b.rows, b.pending); err == nil {
+		inserted = b.rows
+		if err = b.savepoint("RELEASE SAVEPOINT"); err != nil {
+			return fmt.Errorf("%w: %w", ErrInsertRow, err)
+		}
+	} else {
+		if ee := b.savepoint("ROLLBACK TO SAVEPOINT"); ee != nil {
+			return fmt.Errorf("%w: %w", ErrInsertRow, ee)
+		}
+		// insert the rows of the failed batch one at a time, dropping only
+		// the rows which fail on their own
+		cause := err
+		ids, err = nil, nil
+		width := len(b.columns)
+		for idx := 0; idx < b.rows; idx++ {
+			var rowIDs []int64
+			if err = b.savepoint("SAVEPOINT"); err != nil {
+				return fmt.Errorf("%w: %w", ErrInsertRow, err)
+			} else if rowIDs, err = b.insertRows(1, b.pending[idx*width:(idx+1)*width]); err != nil {
+				cause = err
+				dropped = append(dropped, b.numbers[idx])
+				if err = b.savepoint("ROLLBACK TO SAVEPOINT"); err != nil {
+					return fmt.Errorf("%w: %w", ErrInsertRow, err)
+				}
+				continue
+			} else if err = b.savepoint("RELEASE SAVEPOINT"); err != nil {
+				return fmt.Errorf("%w: %w", ErrInsertRow, err)
+			}
+			ids = append(ids, rowIDs...)
+			inserted += 1
+		}
+		if len(ids) != inserted {
+			ids = nil
+		}
+		err = fmt.Errorf("%w: %w: %v: %w", ErrInsertRow, ErrBulkDropped, dropped, cause)
+	}
+
+	b.inserted += int64(inserted)
+	if b.progress != nil && inserted > 0 {
+		b.progress(ids, b.inserted)
+	}
+	return
+}
+
+// savepoint performs the savepoint statement given for the batch savepoint
+func (b *cBulkInserter) savepoint(statement string) (err error) {
+	dialect := b.tx.eql.dialect
+	_, err = b.tx.tx.Exec(statement + " " + dialect.QuoteField("eql_bulk") + dialect.QuerySuffix())
+	return
+}
+
+// insertRows inserts the rows of the values given, returning the ids of the
+// rows inserted when the dialect reports them
+func (b *cBulkInserter) insertRows(rows int, argv []interface{}) (ids []int64, err error) {
+	var stmt *sql.Stmt
+	if stmt, err = b.getStatement(rows); err != nil {
+		return
+	}
+
+	switch b.tx.eql.dialect.Name() {
+	case "postgresql":
+		var result *sql.Rows
+		if result, err = stmt.Query(argv...); err != nil {
+			return
+		}
+		defer result.Close()
+		for result.Next() {
+			var id int64
+			if err = result.Scan(&id); err != nil {
+				return
+			}
+			ids = append(ids, id)
+		}
+		err = result.Err()
+	default:
+		var result sql.Result
+		if result, err = stmt.Exec(argv...); err != nil {
+			return
+		}
+		// the rows of one statement are given consecutive ids, sqlite reports
+		// the last of these and mysql the first
+		if last, ee := result.LastInsertId(); ee == nil && last > 0 {
+			var first int64
+			switch b.tx.eql.dialect.Name() {
+			case "sqlite3":
+				first = last - int64(rows) + 1
+			case "mysql":
+				first = last
+			}
+			if first > 0 {
+				ids = make([]int64, rows)
+				for idx := range ids {
+					ids[idx] = first + int64(idx)
+				}
+			}
+		}
+	}
+	return
+}
+
+func (b *cBulkInserter) Close() (err error) {
+	if b.closed {
+		return
+	}
+	err = b.Flush()
+	for _, stmt := range b.stmts {
+		_ = stmt.Close()
+	}
+	b.stmts = nil
+	b.closed = true
+	return
+}
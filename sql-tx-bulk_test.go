@@ -0,0 +1,112 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+)
+
+func TestBulkInsert(t *testing.T) {
+	Convey("BulkInsert", t, func() {
+
+		eql, tdb := makeTestEQL(makeQfConfig())
+		defer tdb.Close()
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+
+		var progress [][]int64
+		var counts []int64
+		bulk, err := tx.BulkInsert("word", BulkBatchSize(2), BulkOnProgress(func(ids []int64, inserted int64) {
+			progress = append(progress, ids)
+			counts = append(counts, inserted)
+		}))
+		SoMsg("bulk insert error", err, ShouldBeNil)
+		for idx, word := range []string{"one", "two", "three", "four", "five"} {
+			SoMsg(fmt.Sprintf("bulk add #%d error", idx), bulk.Add(word[:1], word, word), ShouldBeNil)
+		}
+		SoMsg("bulk batch size", bulk.BatchSize(), ShouldEqual, 2)
+		SoMsg("bulk inserted before close", bulk.Inserted(), ShouldEqual, 4)
+		SoMsg("bulk mismatched values", bulk.Add("s", "six"), ShouldWrap, ErrBulkValues)
+		SoMsg("bulk invalid value type", bulk.Add("s", "six", struct{}{}), ShouldWrap, ErrBulkValueType)
+		SoMsg("bulk mistyped value", bulk.Add("s", "six", 6), ShouldWrap, ErrBulkValueType)
+		SoMsg("bulk close error", bulk.Close(), ShouldBeNil)
+		SoMsg("bulk inserted after close", bulk.Inserted(), ShouldEqual, 5)
+		SoMsg("bulk add after close", bulk.Add("s", "six", "six"), ShouldWrap, ErrBulkClosed)
+		SoMsg("bulk progress ids", progress, ShouldEqual, [][]int64{{1, 2}, {3, 4}, {5}})
+		SoMsg("bulk progress counts", counts, ShouldEqual, []int64{2, 4, 5})
+
+		bulk, err = tx.BulkInsert("PageWords")
+		SoMsg("bulk insert error", err, ShouldBeNil)
+		for idx := 1; idx <= 1000; idx++ {
+			if err = bulk.Add(idx, idx%5+1, idx); err != nil {
+				break
+			}
+		}
+		SoMsg("bulk add error", err, ShouldBeNil)
+		SoMsg("bulk dialect batch size", bulk.BatchSize(), ShouldBeIn, []int{999 / 3, 32766 / 3})
+		SoMsg("bulk close error", bulk.Close(), ShouldBeNil)
+		SoMsg("bulk inserted", bulk.Inserted(), ShouldEqual, 1000)
+
+		progress = nil
+		bulk, err = tx.BulkInsert("page", BulkBatchSize(3), BulkOnProgress(func(ids []int64, inserted int64) {
+			progress = append(progress, ids)
+		}))
+		SoMsg("bulk insert error", err, ShouldBeNil)
+		SoMsg("bulk add first page", bulk.Add("0123456789"), ShouldBeNil)
+		SoMsg("bulk add mistyped page", bulk.Add(1234567890), ShouldWrap, ErrBulkValueType)
+		SoMsg("bulk add duplicate page", bulk.Add("0123456789"), ShouldBeNil)
+		err = bulk.Add(&[]string{"9876543210"}[0])
+		SoMsg("bulk add pointer value", err, ShouldWrap, ErrBulkDropped)
+		SoMsg("bulk dropped rows", err.Error(), ShouldContainSubstring, ": [3]: ")
+		SoMsg("bulk flush after failure", bulk.Flush(), ShouldBeNil)
+		SoMsg("bulk add last page", bulk.Add("8765432109"), ShouldBeNil)
+		SoMsg("bulk close error", bulk.Close(), ShouldBeNil)
+		SoMsg("bulk inserted", bulk.Inserted(), ShouldEqual, 3)
+		SoMsg("bulk retried progress ids", progress, ShouldEqual, [][]int64{{1, 2}, {3}})
+
+		_, err = tx.BulkInsert("nope")
+		SoMsg("bulk source not found", err, ShouldWrap, ErrSourceNotFound)
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err := eql.Perform(`LOOKUP word.id, word.word ORDER BY word.id`)
+		SoMsg("lookup error", err, ShouldBeNil)
+		SoMsg("lookup results", results, ShouldEqual, clContext.Contexts{
+			{"id": int64(1), "word": "one"},
+			{"id": int64(2), "word": "two"},
+			{"id": int64(3), "word": "three"},
+			{"id": int64(4), "word": "four"},
+			{"id": int64(5), "word": "five"},
+		})
+		_, results, err = eql.Perform(`LOOKUP page.shasum ORDER BY page.id`)
+		SoMsg("lookup pages error", err, ShouldBeNil)
+		SoMsg("lookup pages results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "0123456789"},
+			{"shasum": "9876543210"},
+			{"shasum": "8765432109"},
+		})
+		_, results, err = eql.Perform(`LOOKUP COUNT page_words.id AS total`)
+		SoMsg("count error", err, ShouldBeNil)
+		SoMsg("count results", results, ShouldEqual, clContext.Contexts{
+			{"total": int64(1000)},
+		})
+
+	})
+}
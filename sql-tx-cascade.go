@@ -0,0 +1,159 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+)
+
+// getDependents returns the joins of all the sources referencing the named
+// source, see gSourceGraph.references
+func (c *cSources) getDependents(name string) (dependents []*gSourceJoin) {
+	for _, ref := range c.graph.references() {
+		if ref.other.table == name && ref.this.table != name {
+			dependents = append(dependents, ref)
+		}
+	}
+	return
+}
+
+// prepareCascadeSQL returns the DELETE statements for all the rows depending
+// on the rows selected by the WHERE clause given, dependents first, followed
+// by the DELETE statement of the selected rows. The path prevents circular
+// references from recursing indefinitely
+func (eql *enjinql) prepareCascadeSQL(name, where string, path map[string]struct{}) (statements []string, err error) {
+	var source *cSource
+	var ok bool
+	if source, ok = eql.sources.getSource(name); !ok {
+		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+		return
+	}
+	path[name] = struct{}{}
+	defer delete(path, name)
+
+	table := eql.dialect.QuoteField(source.formal())
+	for _, ref := range eql.sources.getDependents(name) {
+		if _, present := path[ref.this.table]; present {
+			err = fmt.Errorf("%w: %q -> %q", ErrCascadeCycle, name, ref.this.table)
+			return
+		}
+		var dependent *cSource
+		if dependent, ok = eql.sources.getSource(ref.this.table); !ok {
+			err = fmt.Errorf("%w: %q", ErrSourceNotFound, ref.this.table)
+			return
+		}
+		column := eql.dialect.QuoteField(dependent.formal()) + "." + eql.dialect.QuoteField(ref.this.key)
+		selected := table + "." + eql.dialect.QuoteField(ref.other.key)
+		subquery := column + " IN (SELECT " + selected + " FROM " + table + " WHERE " + where + ")"
+		var more []string
+		if more, err = eql.prepareCascadeSQL(dependent.name, subquery, path); err != nil {
+			return
+		}
+		statements = append(statements, more...)
+	}
+
+	statements = append(statements, "DELETE FROM "+table+" WHERE "+where+eql.dialect.QuerySuffix())
+	return
+}
+
+func (c *cSqlTX) DeleteCascade(name string, id int64) (affected int64, err error) {
+	if id <= 0 {
+		err = fmt.Errorf("%w: %w", ErrDeleteRows, ErrInvalidID)
+		return
+	}
+
+	var statements []string
+	c.eql.m.RLock()
+	if source, ok := c.eql.sources.getSource(strcase.ToSnake(name)); !ok {
+		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+	} else {
+		where := c.eql.dialect.QuoteField(source.formal()) + "." + c.eql.dialect.QuoteField(SourceIdKey) +
+			" = " + c.eql.dialect.BindVar(1)
+		statements, err = c.eql.prepareCascadeSQL(source.name, where, make(map[string]struct{}))
+	}
+	c.eql.m.RUnlock()
+	if err != nil {
+		return
+	}
+
+	// dependent rows of any source may be deleted
+	defer c.ids.forget()
+
+	for _, query := range statements {
+		// each statement has the one id placeholder
+		var result sql.Result
+		if result, err = c.tx.Exec(query, id); err != nil {
+			err = fmt.Errorf("%w: %w (%q)", ErrDeleteRows, err, query)
+			return
+		}
+		var count int64
+		if count, err = result.RowsAffected(); err != nil {
+			return
+		}
+		affected += count
+	}
+	return
+}
+
+// makeForeignKeys returns the FOREIGN KEY ... ON DELETE CASCADE clauses for
+// all the parent and linked values of the source given
+func (eql *enjinql) makeForeignKeys(source *cSource) (clauses []string) {
+	for _, ref := range eql.sources.graph.references() {
+		if ref.this.table != source.name {
+			continue
+		} else if other, ok := eql.sources.getSource(ref.other.table); ok {
+			clauses = append(clauses, "FOREIGN KEY ("+eql.dialect.QuoteField(ref.this.key)+") REFERENCES "+
+				eql.dialect.QuoteField(other.formal())+" ("+eql.dialect.QuoteField(ref.other.key)+") ON DELETE CASCADE")
+		}
+	}
+	return
+}
+
+// cForeignKeyDialect is the dialect of CREATE TABLE statements with FOREIGN
+// KEY constraints, which the sql builder does not have a TableOption for, so
+// the constraints are added with the table options
+type cForeignKeyDialect struct {
+	sqlbuilder.Dialect
+	clauses []string
+}
+
+func (d *cForeignKeyDialect) TableOptionToString(option *sqlbuilder.TableOption) (text string, err error) {
+	if text, err = d.Dialect.TableOptionToString(option); err == nil {
+		if text != "" {
+			text += ", "
+		}
+		text += strings.Join(d.clauses, ", ")
+	}
+	return
+}
+
+// createTable returns the CREATE TABLE statement builder of the source given,
+// with the FOREIGN KEY constraints of the source when the CascadeForeignKeys
+// option is set
+func (eql *enjinql) createTable(source *cSource, t sqlbuilder.Table) sqlbuilder.CreateTableBuilder {
+	if eql.option.foreignKeys {
+		if clauses := eql.makeForeignKeys(source); len(clauses) > 0 {
+			d := &cForeignKeyDialect{Dialect: eql.dialect, clauses: clauses}
+			return sqlbuilder.NewBuildable(d).CreateTable(t)
+		}
+	}
+	return eql.builder.CreateTable(t)
+}
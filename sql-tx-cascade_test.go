@@ -0,0 +1,74 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeleteCascade(t *testing.T) {
+	Convey("DeleteCascade", t, func() {
+
+		eql, dbh := makeQfEQL()
+		defer dbh.Close()
+
+		count := func(statement string) (total int64) {
+			_, results, err := eql.Perform(statement)
+			SoMsg("count error", err, ShouldBeNil)
+			if len(results) == 1 {
+				total, _ = results[0]["total"].(int64)
+			}
+			return
+		}
+		totalWords := count(`LOOKUP COUNT page_words.id AS total`)
+		pageWords := count(`LOOKUP COUNT page_words.id AS total WITHIN .id == 1`)
+		SoMsg("page words before", pageWords, ShouldEqual, 7)
+		SoMsg("redirects before", count(`LOOKUP COUNT redirect.id AS total WITHIN .id == 1`), ShouldEqual, 1)
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		affected, err := tx.DeleteCascade(PageSource, 1)
+		SoMsg("delete cascade error", err, ShouldBeNil)
+		SoMsg("delete cascade affected", affected, ShouldEqual, pageWords+2)
+		affected, err = tx.DeleteCascade(PageSource, 1)
+		SoMsg("delete cascade again error", err, ShouldBeNil)
+		SoMsg("delete cascade again affected", affected, ShouldEqual, 0)
+		_, err = tx.DeleteCascade(PageSource, 0)
+		SoMsg("delete cascade invalid id", err, ShouldWrap, ErrInvalidID)
+		_, err = tx.DeleteCascade("nope", 1)
+		SoMsg("delete cascade source not found", err, ShouldWrap, ErrSourceNotFound)
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		SoMsg("pages after", count(`LOOKUP COUNT .id AS total`), ShouldEqual, 1)
+		SoMsg("page words after", count(`LOOKUP COUNT page_words.id AS total`), ShouldEqual, totalWords-pageWords)
+		SoMsg("page words of deleted", count(`LOOKUP COUNT page_words.id AS total WITHIN page_words.page_id == 1`), ShouldEqual, 0)
+
+		Convey("foreign keys", func() {
+			fk, tdb := makeTestEQL(makeQfConfig(), CascadeForeignKeys)
+			defer tdb.Close()
+
+			_, results, err := fk.SqlQuery(`SELECT sql FROM sqlite_master WHERE name = 'qf_eql_page_words'`)
+			SoMsg("sqlite master error", err, ShouldBeNil)
+			SoMsg("sqlite master results", results, ShouldHaveLength, 1)
+			SoMsg("page_words foreign keys", results[0]["sql"], ShouldEndWith,
+				`"hits" INTEGER DEFAULT NULL, `+
+					`FOREIGN KEY ("page_id") REFERENCES "qf_eql_page" ("id") ON DELETE CASCADE, `+
+					`FOREIGN KEY ("word_id") REFERENCES "qf_eql_word" ("id") ON DELETE CASCADE )`)
+		})
+
+	})
+}
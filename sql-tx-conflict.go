@@ -0,0 +1,196 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+)
+
+var (
+	// gConflictFormats are the dialect specific INSERT statement rewrites for
+	// ignoring rows conflicting with any unique constraint, or updating the
+	// other columns of rows conflicting with the unique keys given. Keys and
+	// updates are empty when ignoring conflicts
+	gConflictFormats = map[string]func(dialect sqlbuilder.Dialect, query string, keys, updates []string) string{
+		"sqlite3":    onConflictFormat,
+		"postgresql": onConflictFormat,
+		"mysql": func(dialect sqlbuilder.Dialect, query string, keys, updates []string) string {
+			if len(updates) == 0 {
+				return strings.Replace(query, "INSERT INTO", "INSERT IGNORE INTO", 1)
+			}
+			var sets []string
+			for _, key := range updates {
+				quoted := dialect.QuoteField(key)
+				sets = append(sets, quoted+" = VALUES("+quoted+")")
+			}
+			return query + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+		},
+	}
+)
+
+func onConflictFormat(dialect sqlbuilder.Dialect, query string, keys, updates []string) string {
+	var quoted []string
+	for _, key := range keys {
+		quoted = append(quoted, dialect.QuoteField(key))
+	}
+	if len(updates) == 0 {
+		return query + " ON CONFLICT DO NOTHING"
+	}
+	query += " ON CONFLICT (" + strings.Join(quoted, ", ") + ")"
+	var sets []string
+	for _, key := range updates {
+		field := dialect.QuoteField(key)
+		sets = append(sets, field+" = excluded."+field)
+	}
+	return query + " DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+// getConflictKeys returns the unique constraints of the source given with
+// non-nil values for all of their keys
+func getConflictKeys(source *cSource, keyed map[string]interface{}) (constraints [][]string, err error) {
+	for _, unique := range source.unique {
+		found := len(unique) > 0
+		for _, key := range unique {
+			if value, present := keyed[key]; !present || value == nil {
+				found = false
+				break
+			}
+		}
+		if found {
+			constraints = append(constraints, unique)
+		}
+	}
+	if len(source.unique) == 0 {
+		err = fmt.Errorf("%w: %w (%q)", ErrInsertRow, ErrNoUniqueKeys, source.name)
+	} else if len(constraints) == 0 {
+		err = fmt.Errorf("%w: %w: %q (%q)", ErrInsertRow, ErrUniqueValues, source.unique, source.name)
+	}
+	return
+}
+
+// insertOnConflict inserts the values given, ignoring or updating any row
+// conflicting with a unique constraint, and returns the id of the inserted or
+// conflicting row
+func (c *cSqlTX) insertOnConflict(name string, update bool, values []interface{}) (id int64, err error) {
+	var source *cSource
+	var columns []sqlbuilder.Column
+	if source, columns, err = c.prepareInsertColumns(name, values); err != nil {
+		return
+	}
+	table, _ := source.getTable()
+
+	format, ok := gConflictFormats[c.eql.dialect.Name()]
+	if !ok {
+		err = fmt.Errorf("%w: %w: %q", ErrInsertRow, ErrConflictDialect, c.eql.dialect.Name())
+		return
+	}
+
+	keyed := make(map[string]interface{}, len(values))
+	for idx, value := range values {
+		keyed[source.order[idx]] = value
+	}
+
+	var constraints [][]string
+	if constraints, err = getConflictKeys(source, keyed); err != nil {
+		return
+	}
+
+	var keys, updates []string
+	if update {
+		// only one conflict target can be updated, see SqlTX.Upsert
+		keys = constraints[0]
+		constraints = constraints[:1]
+		unique := make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			unique[key] = struct{}{}
+		}
+		for _, key := range source.order[:len(values)] {
+			if _, present := unique[key]; !present {
+				updates = append(updates, key)
+			}
+		}
+	}
+
+	var query string
+	var argv []interface{}
+	if query, argv, err = c.eql.builder.
+		Insert(table).
+		Columns(columns...).
+		Values(values...).
+		ToSql(); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, err)
+		return
+	}
+	suffix := c.eql.dialect.QuerySuffix()
+	query = format(c.eql.dialect, strings.TrimSuffix(query, suffix), keys, updates) + suffix
+
+	if _, err = c.tx.Exec(query, argv...); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, err)
+		return
+	} else if update {
+		c.ids.forget(source.name)
+	}
+
+	// the last insert id is not reliable for ignored or updated rows, so the
+	// row id is always looked up by the unique constraint values, in order
+	for _, unique := range constraints {
+		if id, err = c.selectConflictID(table, unique, keyed); err == nil || !errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, err)
+	}
+	return
+}
+
+// selectConflictID returns the id of the row with the unique constraint
+// values given
+func (c *cSqlTX) selectConflictID(table sqlbuilder.Table, keys []string, keyed map[string]interface{}) (id int64, err error) {
+	var conditions []sqlbuilder.Condition
+	for _, key := range keys {
+		conditions = append(conditions, table.C(key).Eq(keyed[key]))
+	}
+	var cond sqlbuilder.Condition
+	if len(conditions) == 1 {
+		cond = conditions[0]
+	} else {
+		cond = sqlbuilder.And(conditions...)
+	}
+	var query string
+	var argv []interface{}
+	if query, argv, err = c.eql.builder.
+		Select(table).
+		Columns(table.C(SourceIdKey)).
+		Where(cond).
+		ToSql(); err != nil {
+		return
+	}
+	err = c.tx.QueryRow(query, argv...).Scan(&id)
+	return
+}
+
+func (c *cSqlTX) InsertOrIgnore(name string, values ...interface{}) (id int64, err error) {
+	return c.insertOnConflict(name, false, values)
+}
+
+func (c *cSqlTX) Upsert(name string, values ...interface{}) (id int64, err error) {
+	return c.insertOnConflict(name, true, values)
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+	"github.com/go-corelibs/go-sqlbuilder/dialects"
+)
+
+func TestInsertOnConflict(t *testing.T) {
+	Convey("InsertOrIgnore/Upsert", t, func() {
+
+		config, err := NewConfig("be_eql").
+			AddSource(PageSourceConfig()).
+			NewSource("word").
+			NewStringValue("word", 256).
+			NewStringValue("flat", 256).
+			NewIntValue("seen").
+			AddUnique("word").
+			DoneSource().
+			NewSource("note").
+			NewStringValue("text", 256).
+			DoneSource().
+			Make()
+		SoMsg("config error", err, ShouldBeNil)
+
+		eql, tdb := makeTestEQL(config)
+		defer tdb.Close()
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+
+		id, err := tx.InsertOrIgnore("word", "i'm", "i_m", 1)
+		SoMsg("insert or ignore new error", err, ShouldBeNil)
+		SoMsg("insert or ignore new id", id, ShouldEqual, 1)
+		id, err = tx.InsertOrIgnore("word", "thing", "thing", 1)
+		SoMsg("insert or ignore other error", err, ShouldBeNil)
+		SoMsg("insert or ignore other id", id, ShouldEqual, 2)
+		id, err = tx.InsertOrIgnore("word", "i'm", "im", 2)
+		SoMsg("insert or ignore existing error", err, ShouldBeNil)
+		SoMsg("insert or ignore existing id", id, ShouldEqual, 1)
+
+		id, err = tx.Upsert("word", "thing", "things", 5)
+		SoMsg("upsert existing error", err, ShouldBeNil)
+		SoMsg("upsert existing id", id, ShouldEqual, 2)
+		id, err = tx.Upsert("word", "other", "other", 1)
+		SoMsg("upsert new error", err, ShouldBeNil)
+		SoMsg("upsert new id", id, ShouldEqual, 3)
+
+		_, err = tx.InsertOrIgnore("note", "a note")
+		SoMsg("no unique constraints error", err, ShouldWrap, ErrNoUniqueKeys)
+		_, err = tx.Upsert("word", nil, "nil")
+		SoMsg("no unique values error", err, ShouldWrap, ErrUniqueValues)
+		_, err = tx.Upsert("nope", "nope")
+		SoMsg("source not found error", err, ShouldWrap, ErrSourceNotFound)
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err := eql.Perform(`LOOKUP word.id, word.word, word.flat, word.seen ORDER BY word.id`)
+		SoMsg("lookup error", err, ShouldBeNil)
+		SoMsg("lookup results", results, ShouldEqual, clContext.Contexts{
+			{"id": int64(1), "word": "i'm", "flat": "i_m", "seen": int64(1)},
+			{"id": int64(2), "word": "thing", "flat": "things", "seen": int64(5)},
+			{"id": int64(3), "word": "other", "flat": "other", "seen": int64(1)},
+		})
+
+		Convey("multiple unique constraints", func() {
+			config, err := NewConfig("be_eql").
+				AddSource(PageSourceConfig()).
+				NewSource("tag").
+				NewStringValue("name", 64).
+				NewStringValue("slug", 64).
+				AddUnique("name").
+				AddUnique("slug").
+				DoneSource().
+				Make()
+			SoMsg("config error", err, ShouldBeNil)
+
+			eql, tdb := makeTestEQL(config)
+			defer tdb.Close()
+
+			tx, err := eql.SqlBegin()
+			SoMsg("sql begin err", err, ShouldBeNil)
+			defer tx.Rollback()
+
+			id, err := tx.InsertOrIgnore("tag", "Go", "go")
+			SoMsg("insert or ignore new error", err, ShouldBeNil)
+			SoMsg("insert or ignore new id", id, ShouldEqual, 1)
+			id, err = tx.InsertOrIgnore("tag", "Golang", "go")
+			SoMsg("insert or ignore second constraint error", err, ShouldBeNil)
+			SoMsg("insert or ignore second constraint id", id, ShouldEqual, 1)
+
+			// only the first complete constraint is the upsert conflict target
+			_, err = tx.Upsert("tag", "Golang", "go")
+			SoMsg("upsert second constraint error", err, ShouldWrap, ErrInsertRow)
+
+			id, err = tx.ResolveID("tag", "Go")
+			SoMsg("resolve id error", err, ShouldBeNil)
+			SoMsg("resolve id", id, ShouldEqual, 1)
+			id, err = tx.Upsert("tag", nil, "go")
+			SoMsg("upsert slug error", err, ShouldBeNil)
+			SoMsg("upsert slug id", id, ShouldEqual, 1)
+			_, err = tx.ResolveID("tag", "Go")
+			SoMsg("resolve upserted id error", err, ShouldWrap, ErrIDNotFound)
+		})
+
+		Convey("dialect formats", func() {
+			query := `INSERT INTO "t" ( "word", "flat" ) VALUES ( ?, ? )`
+			sqlite := gConflictFormats["sqlite3"]
+			SoMsg("sqlite ignore", sqlite(dialects.Sqlite{}, query, nil, nil), ShouldEqual,
+				query+` ON CONFLICT DO NOTHING`)
+			SoMsg("sqlite update", sqlite(dialects.Sqlite{}, query, []string{"word"}, []string{"flat"}), ShouldEqual,
+				query+` ON CONFLICT ("word") DO UPDATE SET "flat" = excluded."flat"`)
+			query = "INSERT INTO `t` ( `word`, `flat` ) VALUES ( ?, ? )"
+			mysql := gConflictFormats["mysql"]
+			SoMsg("mysql ignore", mysql(dialects.MySql{}, query, nil, nil), ShouldEqual,
+				"INSERT IGNORE INTO `t` ( `word`, `flat` ) VALUES ( ?, ? )")
+			SoMsg("mysql update", mysql(dialects.MySql{}, query, []string{"word"}, []string{"flat"}), ShouldEqual,
+				query+" ON DUPLICATE KEY UPDATE `flat` = VALUES(`flat`)")
+		})
+
+	})
+}
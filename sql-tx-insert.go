@@ -0,0 +1,207 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/go-corelibs/go-sqlbuilder"
+)
+
+// prepareInsertColumns returns the source and the columns, in source order,
+// for the positional values given
+func (c *cSqlTX) prepareInsertColumns(name string, values []interface{}) (source *cSource, columns []sqlbuilder.Column, err error) {
+	var ok bool
+	if source, ok = c.getSource(name); !ok {
+		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+		return
+	}
+	var table sqlbuilder.Table
+	if table, err = source.getTable(); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, err)
+		return
+	}
+	numValues := len(values)
+	if numValues == 0 {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, ErrNoValues)
+		return
+	}
+
+	for _, columnName := range source.order {
+		columns = append(columns, table.C(columnName))
+	}
+	numColumns := len(columns)
+	if numColumns < numValues {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, ErrTooManyValues)
+		return
+	} else if numColumns > numValues {
+		columns = columns[:numValues]
+	}
+	return
+}
+
+// prepareInsertMap returns the source and the columns and values to insert,
+// in column order, for the values keyed by source key name
+func (c *cSqlTX) prepareInsertMap(name string, values map[string]interface{}) (source *cSource, columns []sqlbuilder.Column, list []interface{}, err error) {
+	var ok bool
+	if source, ok = c.getSource(name); !ok {
+		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+		return
+	} else if len(values) == 0 {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, ErrNoValues)
+		return
+	}
+
+	keyed := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		column := strcase.ToSnake(key)
+		if _, ok = source.getColumn(column); !ok {
+			err = fmt.Errorf("%w: %w: %q (%q keys: %q)", ErrInsertRow, ErrColumnNotFound, key, name, source.order)
+			return
+		} else if _, present := keyed[column]; present {
+			err = fmt.Errorf("%w: %w: %q (%q)", ErrInsertRow, ErrDuplicateKey, key, column)
+			return
+		}
+		keyed[column] = value
+	}
+
+	for _, key := range source.getRequiredKeys() {
+		if value, present := keyed[key]; !present || value == nil {
+			err = fmt.Errorf("%w: %w: %q (%q)", ErrInsertRow, ErrMissingKey, key, name)
+			return
+		}
+	}
+
+	for _, key := range append([]string{SourceIdKey}, source.order...) {
+		if value, present := keyed[key]; present {
+			column, _ := source.getColumn(key)
+			columns = append(columns, column)
+			list = append(list, value)
+		}
+	}
+	return
+}
+
+func (c *cSqlTX) InsertMap(name string, values map[string]interface{}) (id int64, err error) {
+	var source *cSource
+	var columns []sqlbuilder.Column
+	var list []interface{}
+	if source, columns, list, err = c.prepareInsertMap(name, values); err != nil {
+		return
+	}
+	var table sqlbuilder.Table
+	if table, err = source.getTable(); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, err)
+		return
+	}
+
+	var query string
+	var argv []interface{}
+	if query, argv, err = c.eql.builder.
+		Insert(table).
+		Columns(columns...).
+		Values(list...).
+		ToSql(); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, err)
+		return
+	}
+
+	var result sql.Result
+	if result, err = c.tx.Exec(query, argv...); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, err)
+		return
+	}
+
+	id, err = result.LastInsertId()
+	return
+}
+
+func (c *cSqlTX) InsertStruct(name string, v interface{}) (id int64, err error) {
+	var values map[string]interface{}
+	if values, err = structValues(v); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInsertRow, err)
+		return
+	}
+	id, err = c.InsertMap(name, values)
+	return
+}
+
+// structValues returns the exported fields of the struct given which are
+// tagged with source key names, keyed by those names. Untagged embedded
+// structs are included as if their fields were of the struct given
+func structValues(v interface{}) (values map[string]interface{}, err error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		err = fmt.Errorf("%w: %T", ErrInsertStruct, v)
+		return
+	}
+	values = make(map[string]interface{})
+	err = collectStructValues(rv, values)
+	return
+}
+
+func collectStructValues(rv reflect.Value, values map[string]interface{}) (err error) {
+	rt := rv.Type()
+	for idx := 0; idx < rt.NumField(); idx++ {
+		field := rt.Field(idx)
+		tag, tagged := field.Tag.Lookup("eql")
+
+		if !tagged {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err = collectStructValues(rv.Field(idx), values); err != nil {
+					return
+				}
+			}
+			continue
+		} else if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		key, options, _ := strings.Cut(tag, ",")
+		if key == "" {
+			err = fmt.Errorf("%w: %q field tag", ErrMissingSourceKey, field.Name)
+			return
+		}
+
+		value := rv.Field(idx)
+		if options == "omitempty" && value.IsZero() {
+			continue
+		} else if _, present := values[key]; present {
+			err = fmt.Errorf("%w: %q (%q field)", ErrDuplicateKey, key, field.Name)
+			return
+		}
+
+		for value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				break
+			}
+			value = value.Elem()
+		}
+		if value.Kind() == reflect.Pointer {
+			values[key] = nil
+		} else {
+			values[key] = value.Interface()
+		}
+	}
+	return
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+	"github.com/go-corelibs/go-sqlbuilder"
+)
+
+func TestInsertMap(t *testing.T) {
+	Convey("InsertMap/InsertStruct", t, func() {
+
+		eql, tdb := makeTestEQL(makeBeConfig())
+		defer tdb.Close()
+
+		type Common struct {
+			Language string `eql:"language"`
+		}
+		type Page struct {
+			Common
+			ID      int64     `eql:"id,omitempty"`
+			Shasum  string    `eql:"shasum"`
+			Type    string    `eql:"type"`
+			Created time.Time `eql:"created"`
+			URL     *string   `eql:"url"`
+			Ignored string    `eql:"-"`
+			Other   string
+		}
+
+		now, _ := time.Parse("2006-01-02 15:04", "2024-03-17 11:25")
+		url := "/struct"
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+
+		id, err := tx.InsertMap(PageSource, map[string]interface{}{
+			"url":    "/map",
+			"Shasum": "1234567890",
+			"type":   "page",
+		})
+		SoMsg("insert map error", err, ShouldBeNil)
+		SoMsg("insert map id", id, ShouldEqual, 1)
+
+		id, err = tx.InsertStruct(PageSource, &Page{Common: Common{Language: "en"}, Shasum: "0123456789", Type: "blog", Created: now, URL: &url})
+		SoMsg("insert struct error", err, ShouldBeNil)
+		SoMsg("insert struct id", id, ShouldEqual, 2)
+
+		id, err = tx.InsertStruct(PageSource, Page{Shasum: "9012345678"})
+		SoMsg("insert struct nil pointer error", err, ShouldBeNil)
+		SoMsg("insert struct nil pointer id", id, ShouldEqual, 3)
+
+		id, err = tx.InsertMap("page_title", map[string]interface{}{"page_id": 2, "text": "a title"})
+		SoMsg("insert child error", err, ShouldBeNil)
+		SoMsg("insert child id", id, ShouldEqual, 1)
+
+		for idx, test := range []struct {
+			name   string
+			values interface{}
+			err    error
+			text   string
+		}{
+			{name: "nope", values: map[string]interface{}{"url": "/nope"}, err: ErrSourceNotFound},
+			{name: PageSource, values: map[string]interface{}{}, err: ErrNoValues},
+			{name: PageSource, values: map[string]interface{}{"nope": "/nope"}, err: ErrColumnNotFound, text: `"nope" ("page" keys: ["shasum" "language" "type" "archetype" "created" "updated" "url" "stub"])`},
+			{name: PageSource, values: map[string]interface{}{"url": "/one", "URL": "/two"}, err: ErrDuplicateKey},
+			{name: "page_title", values: map[string]interface{}{"text": "orphan"}, err: ErrMissingKey, text: `"page_id" ("page_title")`},
+			{name: "page_title", values: map[string]interface{}{"page_id": nil, "text": "orphan"}, err: ErrMissingKey},
+			{name: PageSource, values: "not a struct", err: ErrInsertStruct},
+			{name: PageSource, values: struct {
+				URL string `eql:",omitempty"`
+			}{}, err: ErrMissingSourceKey},
+		} {
+			var ee error
+			if values, ok := test.values.(map[string]interface{}); ok {
+				_, ee = tx.InsertMap(test.name, values)
+			} else {
+				_, ee = tx.InsertStruct(test.name, test.values)
+			}
+			SoMsg(fmt.Sprintf("insert error test #%d", idx), ee, ShouldWrap, test.err)
+			if test.text != "" {
+				SoMsg(fmt.Sprintf("insert error test #%d text", idx), ee.Error(), ShouldEndWith, test.text)
+			}
+		}
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err := eql.Perform(`LOOKUP .shasum, .language, .type, .url ORDER BY .id`)
+		SoMsg("lookup error", err, ShouldBeNil)
+		SoMsg("lookup results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "1234567890", "language": nil, "type": "page", "url": "/map"},
+			{"shasum": "0123456789", "language": "en", "type": "blog", "url": "/struct"},
+			{"shasum": "9012345678", "language": "", "type": "", "url": nil},
+		})
+
+	})
+
+	Convey("insert table errors", t, func() {
+
+		eql, tdb := makeTestEQL(makeBeConfig())
+		defer tdb.Close()
+
+		// an invalid source value fails to make the table
+		source, _ := eql.(*enjinql).sources.getSource(PageSource)
+		source.table = nil
+		source.column = make(map[string]sqlbuilder.ColumnConfig)
+		source.value.ivt = gInvalidValue
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		defer tx.Rollback()
+
+		_, err = tx.Insert(PageSource, "1234567890")
+		SoMsg("insert error", err, ShouldWrap, ErrInsertRow)
+		_, err = tx.InsertMap(PageSource, map[string]interface{}{"shasum": "1234567890"})
+		SoMsg("insert map error", err, ShouldWrap, ErrInsertRow)
+
+	})
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/iancoleman/strcase"
+)
+
+const (
+	// gResolveCacheSize is the maximum number of ids cached per source by
+	// SqlTX.ResolveID
+	gResolveCacheSize = 4096
+)
+
+// cResolveCache is a per-source LRU cache of natural key values to row ids
+type cResolveCache struct {
+	size    int
+	sources map[string]*cResolveLRU
+	sync.Mutex
+}
+
+type cResolveLRU struct {
+	order  *list.List
+	lookup map[string]*list.Element
+}
+
+type cResolveEntry struct {
+	key string
+	id  int64
+}
+
+func newResolveCache(size int) *cResolveCache {
+	return &cResolveCache{
+		size:    size,
+		sources: make(map[string]*cResolveLRU),
+	}
+}
+
+func (c *cResolveCache) get(name, key string) (id int64, ok bool) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	if lru, present := c.sources[name]; present {
+		var element *list.Element
+		if element, ok = lru.lookup[key]; ok {
+			lru.order.MoveToFront(element)
+			id = element.Value.(*cResolveEntry).id
+		}
+	}
+	return
+}
+
+func (c *cResolveCache) set(name, key string, id int64) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	lru, present := c.sources[name]
+	if !present {
+		lru = &cResolveLRU{order: list.New(), lookup: make(map[string]*list.Element)}
+		c.sources[name] = lru
+	}
+	if element, ok := lru.lookup[key]; ok {
+		element.Value.(*cResolveEntry).id = id
+		lru.order.MoveToFront(element)
+		return
+	}
+	lru.lookup[key] = lru.order.PushFront(&cResolveEntry{key: key, id: id})
+	if lru.order.Len() > c.size {
+		oldest := lru.order.Back()
+		lru.order.Remove(oldest)
+		delete(lru.lookup, oldest.Value.(*cResolveEntry).key)
+	}
+}
+
+// forget removes all the cached ids of the named sources, or of all sources
+// when no names are given
+func (c *cResolveCache) forget(names ...string) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	if len(names) == 0 {
+		c.sources = make(map[string]*cResolveLRU)
+		return
+	}
+	for _, name := range names {
+		delete(c.sources, name)
+	}
+}
+
+// getResolveKey returns the source key to resolve ids by, which is the first
+// single key unique constraint or, when there are none, the primary value
+func getResolveKey(source *cSource) (key string, err error) {
+	for _, unique := range source.unique {
+		if len(unique) == 1 && unique[0] != SourceIdKey {
+			return unique[0], nil
+		}
+	}
+	if source.value.ivt == gLinkValue {
+		err = fmt.Errorf("%w: %q has no natural key", ErrResolveID, source.name)
+		return
+	}
+	key = source.value.key
+	return
+}
+
+func (c *cSqlTX) ResolveID(name string, value interface{}) (id int64, err error) {
+	var source *cSource
+	var ok bool
+	if source, ok = c.getSource(name); !ok {
+		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+		return
+	} else if value == nil {
+		err = fmt.Errorf("%w: %w (%q)", ErrResolveID, ErrNoValues, source.name)
+		return
+	}
+
+	var key string
+	if key, err = getResolveKey(source); err != nil {
+		return
+	}
+
+	cached := fmt.Sprintf("%v", normalizeSyncValue(source, key, value))
+	if id, ok = c.ids.get(source.name, cached); ok {
+		return
+	}
+	table, _ := source.getTable()
+	column, _ := source.getColumn(key)
+
+	var query string
+	var argv []interface{}
+	if query, argv, err = c.eql.builder.
+		Select(table).
+		Columns(table.C(SourceIdKey)).
+		Where(column.Eq(value)).
+		Limit(2).
+		ToSql(); err != nil {
+		err = fmt.Errorf("%w: %w", ErrResolveID, err)
+		return
+	}
+
+	var ids []int64
+	if ids, err = c.queryIDs(query, argv...); err != nil {
+		err = fmt.Errorf("%w: %w", ErrResolveID, err)
+		return
+	}
+	switch len(ids) {
+	case 0:
+		err = fmt.Errorf("%w: %w: %q %s == %v", ErrResolveID, ErrIDNotFound, source.name, key, value)
+		return
+	case 1:
+		id = ids[0]
+	default:
+		err = fmt.Errorf("%w: %w: %q %s == %v", ErrResolveID, ErrAmbiguousID, source.name, key, value)
+		return
+	}
+
+	c.ids.set(source.name, cached, id)
+	return
+}
+
+func (c *cSqlTX) queryIDs(query string, argv ...interface{}) (ids []int64, err error) {
+	rows, err := c.tx.Query(query, argv...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	return
+}
+
+func (c *cSqlTX) InsertLinked(name string, values map[string]interface{}) (id int64, err error) {
+	var source *cSource
+	var ok bool
+	if source, ok = c.getSource(name); !ok {
+		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+		return
+	}
+
+	// the parent id value of child sources is the primary value and is a
+	// linked value like the others
+	linked := make(map[string]struct{})
+	for _, value := range append([]cSourceValue{source.value}, source.values...) {
+		if value.ivt == gLinkValue {
+			linked[value.key] = struct{}{}
+		}
+	}
+
+	resolved := make(map[string]interface{}, len(values))
+	naturals := make(map[string]interface{})
+	for key, value := range values {
+		column := strcase.ToSnake(key)
+		if _, isLinked := linked[column+"_"+SourceIdKey]; isLinked {
+			naturals[column] = value
+		} else if _, present := resolved[column]; present {
+			err = fmt.Errorf("%w: %w: %q (%q)", ErrInsertRow, ErrDuplicateKey, key, column)
+			return
+		} else {
+			resolved[column] = value
+		}
+	}
+
+	for other, value := range naturals {
+		column := other + "_" + SourceIdKey
+		if _, present := resolved[column]; present {
+			err = fmt.Errorf("%w: %w: %q (%q)", ErrInsertRow, ErrDuplicateKey, other, column)
+			return
+		}
+		var otherID int64
+		if otherID, err = c.ResolveID(other, value); err != nil {
+			err = fmt.Errorf("%w: %w", ErrInsertRow, err)
+			return
+		}
+		resolved[column] = otherID
+	}
+
+	id, err = c.InsertMap(name, resolved)
+	return
+}
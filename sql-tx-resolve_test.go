@@ -0,0 +1,116 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResolveID(t *testing.T) {
+	Convey("ResolveID and InsertLinked", t, func() {
+
+		config, err := NewConfig("ids", "eql").
+			AddSource(PageSourceConfig()).
+			NewSource("word").
+			NewStringValue("letter", 1).
+			NewStringValue("word", 256).
+			AddUnique("word").
+			DoneSource().
+			NewSource("page_words").
+			SetParent(PageSource).
+			NewLinkedValue("word", SourceIdKey).
+			NewIntValue("hits").
+			DoneSource().
+			Make()
+		SoMsg("config error", err, ShouldBeNil)
+		eql, dbh := makeTestEQL(config)
+		defer dbh.Close()
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		defer tx.Rollback()
+		pid, err := tx.Insert(PageSource, "1122334455", "en", "quote", "/quote")
+		SoMsg("insert page error", err, ShouldBeNil)
+		wid, err := tx.Insert("word", "t", "thing")
+		SoMsg("insert word error", err, ShouldBeNil)
+		_, err = tx.Insert("word", "o", "other")
+		SoMsg("insert other word error", err, ShouldBeNil)
+
+		id, err := tx.ResolveID(PageSource, "1122334455")
+		SoMsg("resolve page error", err, ShouldBeNil)
+		SoMsg("resolve page", id, ShouldEqual, pid)
+		id, err = tx.ResolveID("word", "thing")
+		SoMsg("resolve word error", err, ShouldBeNil)
+		SoMsg("resolve word", id, ShouldEqual, wid)
+
+		_, err = tx.ResolveID("word", "nope")
+		SoMsg("resolve not found", err, ShouldWrap, ErrIDNotFound)
+		_, err = tx.ResolveID("page_words", 1)
+		SoMsg("resolve child source", err, ShouldWrap, ErrResolveID)
+		_, err = tx.ResolveID("nope", 1)
+		SoMsg("resolve source not found", err, ShouldWrap, ErrSourceNotFound)
+
+		// changes made directly are not seen while cached
+		_, err = tx.Exec(`UPDATE ids_eql_word SET word = 'changed' WHERE id = ?`, wid)
+		SoMsg("direct update error", err, ShouldBeNil)
+		id, err = tx.ResolveID("word", "thing")
+		SoMsg("resolve cached error", err, ShouldBeNil)
+		SoMsg("resolve cached", id, ShouldEqual, wid)
+		// updates forget the cached ids of the source
+		_, err = tx.Update("word", wid, map[string]interface{}{"word": "thing"})
+		SoMsg("update word error", err, ShouldBeNil)
+		_, err = tx.ResolveID("word", "changed")
+		SoMsg("resolve changed", err, ShouldWrap, ErrIDNotFound)
+
+		lid, err := tx.InsertLinked("PageWords", map[string]interface{}{"page": "1122334455", "word": "thing", "hits": 3})
+		SoMsg("insert linked error", err, ShouldBeNil)
+		SoMsg("insert linked id", lid, ShouldEqual, 1)
+		var pageId, wordId, hits int64
+		err = tx.QueryRow(`SELECT page_id, word_id, hits FROM ids_eql_page_words WHERE id = ?`, lid).Scan(&pageId, &wordId, &hits)
+		SoMsg("insert linked query error", err, ShouldBeNil)
+		SoMsg("insert linked values", []int64{pageId, wordId, hits}, ShouldEqual, []int64{pid, wid, 3})
+
+		_, err = tx.InsertLinked("page_words", map[string]interface{}{"page": "1122334455", "word": "nope"})
+		SoMsg("insert linked not found", err, ShouldWrap, ErrIDNotFound)
+		_, err = tx.InsertLinked("page_words", map[string]interface{}{"page": "1122334455", "word": "other", "word_id": wid})
+		SoMsg("insert linked duplicate", err, ShouldWrap, ErrDuplicateKey)
+
+		// deletes forget the cached ids of the source
+		_, err = tx.Delete("word", wid)
+		SoMsg("delete word error", err, ShouldBeNil)
+		_, err = tx.ResolveID("word", "thing")
+		SoMsg("resolve deleted", err, ShouldWrap, ErrIDNotFound)
+
+	})
+}
+
+func TestResolveIDAmbiguity(t *testing.T) {
+	Convey("ResolveID ambiguity", t, func() {
+		eql, dbh := makeQfEQL()
+		defer dbh.Close()
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		defer tx.Rollback()
+		// the qf word source has no unique constraints and is resolved by
+		// the first letter
+		_, err = tx.ResolveID("word", "t")
+		SoMsg("resolve ambiguous", err, ShouldWrap, ErrAmbiguousID)
+		id, err := tx.ResolveID("word", "b")
+		SoMsg("resolve letter error", err, ShouldBeNil)
+		SoMsg("resolve letter", id, ShouldEqual, 12)
+	})
+}
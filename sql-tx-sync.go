@@ -0,0 +1,290 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/iancoleman/strcase"
+)
+
+// SyncSummary describes the changes applied by SqlTX.SyncChildren
+type SyncSummary struct {
+	// Inserted are the ids of the rows inserted, in the order desired
+	Inserted []int64
+	// Updated are the ids of the rows updated, in the order desired
+	Updated []int64
+	// Deleted are the ids of the rows deleted, in id order
+	Deleted []int64
+	// Unchanged is the number of desired rows already present as-is
+	Unchanged int
+}
+
+// Changed returns true if any rows were inserted, updated or deleted
+func (s *SyncSummary) Changed() bool {
+	return len(s.Inserted)+len(s.Updated)+len(s.Deleted) > 0
+}
+
+type cSyncRow struct {
+	id     int64
+	values map[string]interface{}
+}
+
+// getSyncKeys returns the first unique constraint of the child source, less
+// the parent key
+func getSyncKeys(child *cSource) (keys []string, err error) {
+	if len(child.unique) == 0 {
+		err = fmt.Errorf("%w: %q", ErrNoUniqueKeys, child.name)
+		return
+	}
+	for _, key := range child.unique[0] {
+		if key != child.value.key {
+			keys = append(keys, key)
+		}
+	}
+	return
+}
+
+// makeSyncKey returns the unique constraint values of the row given as a
+// single comparable string
+func makeSyncKey(child *cSource, keys []string, values map[string]interface{}) (key string) {
+	var parts []string
+	for _, name := range keys {
+		parts = append(parts, fmt.Sprintf("%v", normalizeSyncValue(child, name, values[name])))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// normalizeSyncValue converts the value given to the types scanned from the
+// database for the named source key, for comparing desired values with
+// existing ones
+func normalizeSyncValue(child *cSource, name string, value interface{}) interface{} {
+	value = normalizeScanValue(value)
+	switch child.getValueType(name) {
+	case gIntValue, gLinkValue, gBoolValue:
+		if v, ok := value.(float64); ok && v == float64(int64(v)) {
+			return int64(v)
+		}
+	case gFloatValue:
+		if v, ok := value.(int64); ok {
+			return float64(v)
+		}
+	}
+	return value
+}
+
+// normalizeScanValue converts the value given to the types scanned from the
+// database
+func normalizeScanValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	}
+	return value
+}
+
+// prepareSyncRows validates the desired rows and returns them keyed by their
+// source key names, in the order given
+func prepareSyncRows(child *cSource, keys []string, desired []map[string]interface{}) (rows []map[string]interface{}, err error) {
+	seen := make(map[string]struct{}, len(desired))
+	for idx, row := range desired {
+		keyed := make(map[string]interface{}, len(row))
+		for key, value := range row {
+			column := strcase.ToSnake(key)
+			if _, ok := child.getColumn(column); !ok || column == SourceIdKey || column == child.value.key {
+				err = fmt.Errorf("%w: %q (%q row #%d)", ErrColumnNotFound, key, child.name, idx+1)
+				return
+			} else if _, present := keyed[column]; present {
+				err = fmt.Errorf("%w: %q (%q row #%d)", ErrDuplicateKey, key, child.name, idx+1)
+				return
+			}
+			keyed[column] = value
+		}
+		for _, key := range keys {
+			if _, present := keyed[key]; !present {
+				err = fmt.Errorf("%w: %q (%q row #%d)", ErrMissingKey, key, child.name, idx+1)
+				return
+			}
+		}
+		unique := makeSyncKey(child, keys, keyed)
+		if _, present := seen[unique]; present {
+			err = fmt.Errorf("%w: %q (%q row #%d)", ErrDuplicateKey, keys, child.name, idx+1)
+			return
+		}
+		seen[unique] = struct{}{}
+		rows = append(rows, keyed)
+	}
+	return
+}
+
+// querySyncRows returns the existing rows of the child source belonging to
+// the parent id given
+func (c *cSqlTX) querySyncRows(child *cSource, parentID int64) (existing []*cSyncRow, err error) {
+	dialect := c.eql.dialect
+	table := dialect.QuoteField(child.formal())
+	columns := []string{dialect.QuoteField(SourceIdKey)}
+	var names []string
+	for _, key := range child.order {
+		if key != child.value.key {
+			names = append(names, key)
+			columns = append(columns, dialect.QuoteField(key))
+		}
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM " + table +
+		" WHERE " + dialect.QuoteField(child.value.key) + " = " + dialect.BindVar(1) +
+		" ORDER BY " + dialect.QuoteField(SourceIdKey) + dialect.QuerySuffix()
+
+	var rows *sql.Rows
+	if rows, err = c.tx.Query(query, parentID); err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		row := &cSyncRow{values: make(map[string]interface{}, len(names))}
+		scanned := make([]interface{}, len(names))
+		dest := []interface{}{&row.id}
+		for idx := range scanned {
+			dest = append(dest, &scanned[idx])
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return
+		}
+		for idx, name := range names {
+			row.values[name] = normalizeSyncValue(child, name, scanned[idx])
+		}
+		existing = append(existing, row)
+	}
+	err = rows.Err()
+	return
+}
+
+func (c *cSqlTX) SyncChildren(parent string, parentID int64, child string, desired []map[string]interface{}) (summary *SyncSummary, err error) {
+	if parentID <= 0 {
+		err = fmt.Errorf("%w: %w", ErrSyncChildren, ErrInvalidID)
+		return
+	}
+
+	c.eql.m.RLock()
+	childSource, ok := c.eql.sources.getSource(strcase.ToSnake(child))
+	if !ok {
+		err = fmt.Errorf("%w: %q", ErrSourceNotFound, child)
+	} else if childSource.parent == nil || childSource.parent.name != strcase.ToSnake(parent) {
+		err = fmt.Errorf("%w: %w: %q of %q", ErrSyncChildren, ErrNotChildSource, child, parent)
+	}
+	c.eql.m.RUnlock()
+	if err != nil {
+		return
+	}
+
+	var keys []string
+	var rows []map[string]interface{}
+	var existing []*cSyncRow
+	if keys, err = getSyncKeys(childSource); err != nil {
+		err = fmt.Errorf("%w: %w", ErrSyncChildren, err)
+		return
+	} else if rows, err = prepareSyncRows(childSource, keys, desired); err != nil {
+		err = fmt.Errorf("%w: %w", ErrSyncChildren, err)
+		return
+	} else if existing, err = c.querySyncRows(childSource, parentID); err != nil {
+		err = fmt.Errorf("%w: %w", ErrSyncChildren, err)
+		return
+	}
+
+	lookup := make(map[string]*cSyncRow, len(existing))
+	for _, row := range existing {
+		lookup[makeSyncKey(childSource, keys, row.values)] = row
+	}
+
+	type cSyncUpdate struct {
+		id      int64
+		changed map[string]interface{}
+	}
+	var inserts []map[string]interface{}
+	var updates []*cSyncUpdate
+	summary = &SyncSummary{}
+	for _, row := range rows {
+		unique := makeSyncKey(childSource, keys, row)
+		if present, found := lookup[unique]; found {
+			delete(lookup, unique)
+			changed := make(map[string]interface{})
+			for key, value := range row {
+				if normalizeSyncValue(childSource, key, value) != present.values[key] {
+					changed[key] = value
+				}
+			}
+			if len(changed) == 0 {
+				summary.Unchanged += 1
+			} else {
+				updates = append(updates, &cSyncUpdate{id: present.id, changed: changed})
+			}
+		} else {
+			inserts = append(inserts, row)
+		}
+	}
+
+	// deletes are first, freeing their unique values for the inserts
+	for _, row := range existing {
+		if _, stale := lookup[makeSyncKey(childSource, keys, row.values)]; stale {
+			if _, err = c.DeleteCascade(childSource.name, row.id); err != nil {
+				err = fmt.Errorf("%w: %w", ErrSyncChildren, err)
+				return
+			}
+			summary.Deleted = append(summary.Deleted, row.id)
+		}
+	}
+
+	for _, update := range updates {
+		if _, err = c.Update(childSource.name, update.id, update.changed); err != nil {
+			err = fmt.Errorf("%w: %w", ErrSyncChildren, err)
+			return
+		}
+		summary.Updated = append(summary.Updated, update.id)
+	}
+
+	for _, row := range inserts {
+		row[childSource.value.key] = parentID
+		var id int64
+		if id, err = c.InsertMap(childSource.name, row); err != nil {
+			err = fmt.Errorf("%w: %w", ErrSyncChildren, err)
+			return
+		}
+		summary.Inserted = append(summary.Inserted, id)
+	}
+	return
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSyncChildren(t *testing.T) {
+	Convey("SyncChildren", t, func() {
+
+		config, err := NewConfig("sync", "eql").
+			AddSource(PageSourceConfig()).
+			NewSource("page_tags").
+			SetParent(PageSource).
+			NewStringValue("tag", 64).
+			NewIntValue("weight").
+			NewFloatValue("score").
+			AddUnique("page_id", "tag").
+			DoneSource().
+			Make()
+		SoMsg("config error", err, ShouldBeNil)
+		eql, dbh := makeTestEQL(config)
+		defer dbh.Close()
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		pid, err := tx.Insert(PageSource, "1122334455", "en", "quote", "/quote")
+		SoMsg("insert page error", err, ShouldBeNil)
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		sync := func(parent string, parentID int64, child string, desired []map[string]interface{}) (summary *SyncSummary, err error) {
+			tx, err := eql.SqlBegin()
+			SoMsg("sql begin err", err, ShouldBeNil)
+			if summary, err = tx.SyncChildren(parent, parentID, child, desired); err != nil {
+				SoMsg("sql rollback err", tx.Rollback(), ShouldBeNil)
+			} else {
+				SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+			}
+			return
+		}
+
+		tags := func() (found map[string]int64) {
+			_, results, ee := eql.SqlQuery(`SELECT tag, weight FROM sync_eql_page_tags WHERE page_id = ?`, pid)
+			SoMsg("query tags error", ee, ShouldBeNil)
+			found = make(map[string]int64)
+			for _, result := range results {
+				found[result.String("tag", "")], _ = result["weight"].(int64)
+			}
+			return
+		}
+
+		summary, err := sync(PageSource, pid, "page_tags", []map[string]interface{}{
+			{"tag": "one", "weight": 1},
+			{"tag": "two", "weight": 2},
+			{"tag": "three", "weight": 3},
+		})
+		SoMsg("sync initial error", err, ShouldBeNil)
+		SoMsg("sync initial summary", summary, ShouldEqual, &SyncSummary{Inserted: []int64{1, 2, 3}})
+		SoMsg("sync initial tags", tags(), ShouldEqual, map[string]int64{"one": 1, "two": 2, "three": 3})
+
+		summary, err = sync(PageSource, pid, "page_tags", []map[string]interface{}{
+			{"tag": "four", "weight": 4},
+			{"tag": "two", "weight": 2},
+			{"tag": "one", "weight": 10},
+		})
+		SoMsg("sync changes error", err, ShouldBeNil)
+		SoMsg("sync changes summary", summary, ShouldEqual, &SyncSummary{
+			Inserted:  []int64{3}, // sqlite reuses the deleted rowid
+			Updated:   []int64{1},
+			Deleted:   []int64{3},
+			Unchanged: 1,
+		})
+		SoMsg("sync changes tags", tags(), ShouldEqual, map[string]int64{"one": 10, "two": 2, "four": 4})
+
+		summary, err = sync(PageSource, pid, "page_tags", []map[string]interface{}{
+			{"tag": "four", "weight": 4},
+			{"tag": "two"},
+			{"Tag": "one", "Weight": 10},
+		})
+		SoMsg("sync same error", err, ShouldBeNil)
+		SoMsg("sync same summary", summary, ShouldEqual, &SyncSummary{Unchanged: 3})
+		SoMsg("sync same changed", summary.Changed(), ShouldBeFalse)
+
+		summary, err = sync(PageSource, pid, "PageTags", []map[string]interface{}{
+			{"tag": "four", "weight": 4, "score": 2},
+			{"tag": "two"},
+			{"tag": "one", "weight": 10},
+		})
+		SoMsg("sync score error", err, ShouldBeNil)
+		SoMsg("sync score summary", summary, ShouldEqual, &SyncSummary{Updated: []int64{3}, Unchanged: 2})
+
+		// integer and float values are compared by the column type
+		summary, err = sync(PageSource, pid, "PageTags", []map[string]interface{}{
+			{"tag": "four", "weight": 4.0, "score": 2},
+			{"tag": "two"},
+			{"tag": "one", "weight": float32(10)},
+		})
+		SoMsg("sync numeric error", err, ShouldBeNil)
+		SoMsg("sync numeric summary", summary, ShouldEqual, &SyncSummary{Unchanged: 3})
+
+		_, err = sync(PageSource, pid, "page_tags", []map[string]interface{}{{"weight": 1}})
+		SoMsg("sync missing key", err, ShouldWrap, ErrMissingKey)
+		_, err = sync(PageSource, pid, "page_tags", []map[string]interface{}{{"tag": "one"}, {"tag": "one"}})
+		SoMsg("sync duplicate key", err, ShouldWrap, ErrDuplicateKey)
+		_, err = sync(PageSource, pid, "page_tags", []map[string]interface{}{{"tag": "one", "page_id": pid}})
+		SoMsg("sync parent key", err, ShouldWrap, ErrColumnNotFound)
+		_, err = sync("page_tags", pid, PageSource, nil)
+		SoMsg("sync not child", err, ShouldWrap, ErrNotChildSource)
+		_, err = sync(PageSource, 0, "page_tags", nil)
+		SoMsg("sync invalid id", err, ShouldWrap, ErrInvalidID)
+
+		summary, err = sync(PageSource, pid, "page_tags", nil)
+		SoMsg("sync none error", err, ShouldBeNil)
+		SoMsg("sync none summary", summary, ShouldEqual, &SyncSummary{Deleted: []int64{1, 2, 3}})
+		SoMsg("sync none tags", tags(), ShouldBeEmpty)
+
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/iancoleman/strcase"
+)
+
+// prepareModifySets returns the values keyed by source key name as UPDATE
+// sets, in column order
+func prepareModifySets(source *cSource, values map[string]interface{}) (sets []*cModifySet, err error) {
+	if len(values) == 0 {
+		err = ErrNoValues
+		return
+	}
+
+	keyed := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		column := strcase.ToSnake(key)
+		if _, ok := source.getColumn(column); !ok || column == SourceIdKey {
+			err = fmt.Errorf("%w: %q (%q keys: %q)", ErrColumnNotFound, key, source.name, source.order)
+			return
+		} else if _, present := keyed[column]; present {
+			err = fmt.Errorf("%w: %q (%q)", ErrDuplicateKey, key, column)
+			return
+		}
+		keyed[column] = value
+	}
+
+	for _, key := range source.order {
+		if value, present := keyed[key]; present {
+			sets = append(sets, &cModifySet{key: key, value: value})
+		}
+	}
+	return
+}
+
+// updateWithin performs the UPDATE of the named source rows within the
+// expression given
+func (c *cSqlTX) updateWithin(name string, within *Expression, values map[string]interface{}) (affected int64, err error) {
+	var query string
+	var argv []interface{}
+
+	c.eql.m.RLock()
+	if source, ok := c.eql.sources.getSource(strcase.ToSnake(name)); !ok {
+		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
+	} else {
+		var sets []*cModifySet
+		if sets, err = prepareModifySets(source, values); err == nil {
+			query, argv, err = c.eql.prepareWithinSQL(name, within, within.Pos, sets)
+		}
+	}
+	c.eql.m.RUnlock()
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrUpdateRows, err)
+		return
+	}
+
+	var result sql.Result
+	if result, err = c.tx.Exec(query, argv...); err != nil {
+		err = fmt.Errorf("%w: %w", ErrUpdateRows, err)
+		return
+	}
+
+	c.ids.forget(strcase.ToSnake(name))
+	affected, err = result.RowsAffected()
+	return
+}
+
+func (c *cSqlTX) Update(name string, id int64, values map[string]interface{}) (affected int64, err error) {
+	if id <= 0 {
+		err = fmt.Errorf("%w: %w", ErrUpdateRows, ErrInvalidID)
+		return
+	}
+	var within *Expression
+	if within, err = gFilterParser.ParseString("update", strcase.ToSnake(name)+".id == "+strconv.FormatInt(id, 10)); err != nil {
+		err = fmt.Errorf("%w: %w", ErrUpdateRows, err)
+		return
+	}
+	affected, err = c.updateWithin(name, within, values)
+	return
+}
+
+func (c *cSqlTX) UpdateWhere(name, within string, values map[string]interface{}, argv ...interface{}) (affected int64, err error) {
+	var prepared string
+	var expression *Expression
+	if prepared, err = PrepareSyntax(within, argv...); err != nil {
+		err = fmt.Errorf("%w: %w", ErrUpdateRows, err)
+		return
+	} else if expression, err = gFilterParser.ParseString("enjinql", prepared); err != nil {
+		err = fmt.Errorf("%w: %w", ErrUpdateRows, err)
+		return
+	} else if err = expression.validate(); err != nil {
+		err = fmt.Errorf("%w: %w", ErrUpdateRows, err)
+		return
+	}
+	affected, err = c.updateWithin(name, expression, values)
+	return
+}
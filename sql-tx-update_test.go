@@ -0,0 +1,96 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	clContext "github.com/go-corelibs/context"
+)
+
+func TestUpdate(t *testing.T) {
+	Convey("Update/UpdateWhere", t, func() {
+
+		eql, dbh := makeQfEQL()
+		defer dbh.Close()
+
+		later, _ := time.Parse("2006-01-02 15:04", "2024-03-17 11:25")
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+
+		affected, err := tx.Update(PageSource, 2, map[string]interface{}{"updated": later, "Type": "blog"})
+		SoMsg("update error", err, ShouldBeNil)
+		SoMsg("update affected", affected, ShouldEqual, 1)
+
+		affected, err = tx.UpdateWhere("page_words", `word.word == {1}`, map[string]interface{}{"hits": 7}, "quote")
+		SoMsg("update where error", err, ShouldBeNil)
+		SoMsg("update where affected", affected, ShouldEqual, 2)
+
+		affected, err = tx.UpdateWhere(PageSource, `(.type == "quote") AND (.shasum == {1})`, map[string]interface{}{"language": "fr"}, "1122334455")
+		SoMsg("update where primary error", err, ShouldBeNil)
+		SoMsg("update where primary affected", affected, ShouldEqual, 1)
+
+		for idx, test := range []struct {
+			err    error
+			update func() (int64, error)
+		}{
+			{err: ErrInvalidID, update: func() (int64, error) {
+				return tx.Update(PageSource, 0, map[string]interface{}{"type": "nope"})
+			}},
+			{err: ErrNoValues, update: func() (int64, error) {
+				return tx.Update(PageSource, 1, nil)
+			}},
+			{err: ErrColumnNotFound, update: func() (int64, error) {
+				return tx.Update(PageSource, 1, map[string]interface{}{"nope": "nope"})
+			}},
+			{err: ErrColumnNotFound, update: func() (int64, error) {
+				return tx.Update(PageSource, 1, map[string]interface{}{"id": 10})
+			}},
+			{err: ErrSourceNotFound, update: func() (int64, error) {
+				return tx.Update("nope", 1, map[string]interface{}{"type": "nope"})
+			}},
+			{err: ErrUpdateRows, update: func() (int64, error) {
+				return tx.UpdateWhere(PageSource, `.type ==`, map[string]interface{}{"type": "nope"})
+			}},
+		} {
+			affected, ee := test.update()
+			SoMsg(fmt.Sprintf("update error test #%d", idx), ee, ShouldWrap, test.err)
+			SoMsg(fmt.Sprintf("update error test #%d affected", idx), affected, ShouldEqual, 0)
+		}
+		SoMsg("sql commit err", tx.Commit(), ShouldBeNil)
+
+		_, results, err := eql.Perform(`LOOKUP .type, .updated WITHIN .id == 2`)
+		SoMsg("lookup error", err, ShouldBeNil)
+		SoMsg("lookup results", results, ShouldEqual, clContext.Contexts{
+			{"type": "blog", "updated": later},
+		})
+		_, results, err = eql.Perform(`LOOKUP DISTINCT page_words.hits WITHIN word.word == "quote"`)
+		SoMsg("lookup error", err, ShouldBeNil)
+		SoMsg("lookup results", results, ShouldEqual, clContext.Contexts{
+			{"hits": int64(7)},
+		})
+		_, results, err = eql.Perform(`LOOKUP .shasum WITHIN .language == "fr"`)
+		SoMsg("lookup error", err, ShouldBeNil)
+		SoMsg("lookup results", results, ShouldEqual, clContext.Contexts{
+			{"shasum": "1122334455"},
+		})
+
+	})
+}
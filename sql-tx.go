@@ -19,6 +19,8 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/iancoleman/strcase"
+
 	clContext "github.com/go-corelibs/context"
 	"github.com/go-corelibs/go-sqlbuilder"
 )
@@ -26,23 +28,147 @@ import (
 var _ SqlTX = (*cSqlTX)(nil)
 
 type SqlTX interface {
+	// SqlDB methods, including Perform, are performed within the transaction
+	// and see all of its uncommitted changes
 	SqlDB
 
+	// Plan is EnjinQL.Plan, for the statements to be performed within the
+	// transaction
+	Plan(format string, args ...interface{}) (brief, verbose string, err error)
+	// ToSQL is EnjinQL.ToSQL, for the statements to be performed within the
+	// transaction
+	ToSQL(format string, args ...interface{}) (query string, argv []interface{}, err error)
+	// PerformPage is EnjinQL.PerformPage, performed within the transaction
+	PerformPage(cursor, format string, argv ...interface{}) (columns []string, results clContext.Contexts, next string, err error)
+	// Paginate is EnjinQL.Paginate, with the count and the page queried
+	// within the transaction
+	Paginate(format string, page, perPage int, argv ...interface{}) (paginated *Paginated, err error)
+	// Facets is EnjinQL.Facets, performed within the transaction
+	Facets(within string, keys []string, argv ...interface{}) (facets []*Facet, err error)
+	// PerformFacets is EnjinQL.PerformFacets, performed within the transaction
+	PerformFacets(format string, argv ...interface{}) (facets []*Facet, err error)
+
 	Insert(name string, values ...interface{}) (id int64, err error)
+	// InsertMap inserts a new row with the values keyed by source key name,
+	// keys not given are left to the database defaults
+	InsertMap(name string, values map[string]interface{}) (id int64, err error)
+	// InsertLinked is InsertMap with the natural key values of linked
+	// sources given by source name, resolved with ResolveID. The parent of a
+	// child source is a linked source too, for example with page_words being
+	// a child of page, linked to word:
+	//
+	//	tx.InsertLinked("page_words", map[string]interface{}{"page": "abc123", "word": "thing", "hits": 1})
+	InsertLinked(name string, values map[string]interface{}) (id int64, err error)
+	// ResolveID returns the id of the named source row with the natural key
+	// value given, which is the source key of the first single key unique
+	// constraint or, when there are none, the primary value. Resolved ids are
+	// cached for the duration of the transaction and forgotten when rows of
+	// the source are deleted or updated
+	ResolveID(name string, value interface{}) (id int64, err error)
+	// InsertOrIgnore is Insert for sources with unique constraints, returning
+	// the id of the existing row when the values given conflict with it on
+	// any of the unique constraints
+	InsertOrIgnore(name string, values ...interface{}) (id int64, err error)
+	// Upsert is Insert for sources with unique constraints, updating all the
+	// other values given of the existing row when the values given conflict
+	// with it and returning its id. Only the first unique constraint with
+	// values for all of its keys is the conflict target, conflicts with any
+	// other unique constraint are errors, except with MySQL which updates
+	// the row conflicting with any of them
+	Upsert(name string, values ...interface{}) (id int64, err error)
+	// InsertStruct inserts a new row with the exported struct fields tagged
+	// with their source key names, for example:
+	//
+	//	type Page struct {
+	//	    ID  int64  `eql:"id,omitempty"`
+	//	    URL string `eql:"url"`
+	//	}
+	//
+	// Fields tagged with omitempty are not inserted when zero valued
+	InsertStruct(name string, v interface{}) (id int64, err error)
+	// BulkInsert returns a new BulkInserter for the named source, batching the
+	// rows added into multi-row INSERT statements sized to the bind variable
+	// limit of the dialect
+	BulkInsert(name string, options ...BulkOption) (bulk BulkInserter, err error)
+	// Update sets the values keyed by source key name of the named source row
+	Update(name string, id int64, values map[string]interface{}) (affected int64, err error)
+	// UpdateWhere sets the values keyed by source key name of all the named
+	// source rows within the EQL expression given, for example:
+	//
+	//	tx.UpdateWhere("page_words", `word.word == {1}`, map[string]interface{}{"hits": 0}, "thing")
+	UpdateWhere(name, within string, values map[string]interface{}, argv ...interface{}) (affected int64, err error)
 	Delete(name string, id int64) (affected int64, err error)
+	// DeleteCascade deletes the named source row and all the rows of other
+	// sources with parent or linked values referencing it, dependents first,
+	// returning the total number of rows deleted
+	DeleteCascade(name string, id int64) (affected int64, err error)
 	DeleteWhereEQ(sourceName, key string, value interface{}) (affected int64, err error)
+	// SyncChildren makes the child source rows of the parent row given match
+	// the desired rows, keyed by source key name. Rows are matched by the first
+	// unique constraint of the child source, less the parent key, and only the
+	// rows which differ are inserted, updated or deleted. Desired rows do not
+	// include the parent key and any keys omitted are left as-is when updating
+	SyncChildren(parent string, parentID int64, child string, desired []map[string]interface{}) (summary *SyncSummary, err error)
+
+	// Execute performs an EQL INSERT, UPDATE or DELETE statement, returning
+	// the id of the row inserted and the number of rows affected
+	Execute(format string, argv ...interface{}) (id int64, affected int64, err error)
 }
 
 type cSqlTX struct {
 	tx  *sql.Tx
 	eql *enjinql
+	ids *cResolveCache
 }
 
 func (c *cSqlTX) Perform(format string, argv ...interface{}) (columns []string, results clContext.Contexts, err error) {
-	columns, results, err = c.eql.Perform(format, argv...)
+	if err = c.eql.Ready(); err == nil {
+		var query string
+		var args []interface{}
+		if query, args, err = c.eql.ToSQL(format, argv...); err != nil {
+			return
+		}
+
+		// query within the transaction to see the uncommitted changes
+		var rows *sql.Rows
+		if rows, err = c.tx.Query(query, args...); err == nil {
+			columns, results = scanContexts(rows)
+		}
+	}
+	return
+}
+
+func (c *cSqlTX) Plan(format string, args ...interface{}) (brief, verbose string, err error) {
+	brief, verbose, err = c.eql.Plan(format, args...)
+	return
+}
+
+func (c *cSqlTX) ToSQL(format string, args ...interface{}) (query string, argv []interface{}, err error) {
+	query, argv, err = c.eql.ToSQL(format, args...)
 	return
 }
 
+func (c *cSqlTX) PerformPage(cursor, format string, argv ...interface{}) (columns []string, results clContext.Contexts, next string, err error) {
+	return c.eql.performPage(c.withTX, cursor, format, argv...)
+}
+
+func (c *cSqlTX) Paginate(format string, page, perPage int, argv ...interface{}) (paginated *Paginated, err error) {
+	return c.eql.paginate(c.withTX, format, page, perPage, argv...)
+}
+
+func (c *cSqlTX) Facets(within string, keys []string, argv ...interface{}) (facets []*Facet, err error) {
+	return c.PerformFacets(makeFacetsFormat(within, keys), argv...)
+}
+
+func (c *cSqlTX) PerformFacets(format string, argv ...interface{}) (facets []*Facet, err error) {
+	return c.eql.performFacets(c.withTX, format, argv...)
+}
+
+// withTX runs fn within the transaction, to see all of its uncommitted changes
+func (c *cSqlTX) withTX(fn func(db SqlDB) (err error)) (err error) {
+	return fn(c)
+}
+
 func (c *cSqlTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
 	return c.tx.PrepareContext(ctx, query)
 }
@@ -76,31 +202,12 @@ func (c *cSqlTX) QueryRow(query string, args ...any) *sql.Row {
 }
 
 func (c *cSqlTX) Insert(name string, values ...interface{}) (id int64, err error) {
-	var ok bool
 	var source *cSource
-	if source, ok = c.eql.sources.getSource(name); !ok {
-		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
-		return
-	}
-	table, _ := source.getTable()
-	numValues := len(values)
-	if numValues == 0 {
-		err = fmt.Errorf("%w: %w", ErrInsertRow, ErrNoValues)
-		return
-	}
-
 	var columns []sqlbuilder.Column
-	for _, columnName := range source.order {
-		columns = append(columns, table.C(columnName))
-	}
-	//columns = columns[1:] // drop the id column
-	numColumns := len(columns)
-	if numColumns < numValues {
-		err = fmt.Errorf("%w: %w", ErrInsertRow, ErrTooManyValues)
+	if source, columns, err = c.prepareInsertColumns(name, values); err != nil {
 		return
-	} else if numColumns > numValues {
-		columns = columns[:numValues]
 	}
+	table, _ := source.getTable()
 
 	b := c.eql.builder.Insert(table)
 	b.Columns(columns...)
@@ -127,10 +234,19 @@ func (c *cSqlTX) Insert(name string, values ...interface{}) (id int64, err error
 	return
 }
 
+// getSource returns the named source, with the name given snake_cased like
+// the source names of statements
+func (c *cSqlTX) getSource(name string) (source *cSource, ok bool) {
+	c.eql.m.RLock()
+	defer c.eql.m.RUnlock()
+	source, ok = c.eql.sources.getSource(strcase.ToSnake(name))
+	return
+}
+
 func (c *cSqlTX) Delete(name string, id int64) (affected int64, err error) {
 	var ok bool
 	var source *cSource
-	if source, ok = c.eql.sources.getSource(name); !ok {
+	if source, ok = c.getSource(name); !ok {
 		err = fmt.Errorf("%w: %q", ErrSourceNotFound, name)
 		return
 	}
@@ -163,6 +279,7 @@ func (c *cSqlTX) Delete(name string, id int64) (affected int64, err error) {
 		return
 	}
 
+	c.ids.forget(source.name)
 	affected, err = result.RowsAffected()
 	return
 }
@@ -170,7 +287,7 @@ func (c *cSqlTX) Delete(name string, id int64) (affected int64, err error) {
 func (c *cSqlTX) DeleteWhereEQ(sourceName, key string, value interface{}) (affected int64, err error) {
 	var ok bool
 	var source *cSource
-	if source, ok = c.eql.sources.getSource(sourceName); !ok {
+	if source, ok = c.getSource(sourceName); !ok {
 		err = fmt.Errorf("%w: %q", ErrSourceNotFound, sourceName)
 		return
 	}
@@ -203,6 +320,7 @@ func (c *cSqlTX) DeleteWhereEQ(sourceName, key string, value interface{}) (affec
 		return
 	}
 
+	c.ids.forget(source.name)
 	affected, err = result.RowsAffected()
 	return
 }
@@ -0,0 +1,83 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSqlTXPerform(t *testing.T) {
+	Convey("transaction Perform", t, func() {
+
+		eql, dbh := makeQfEQL()
+		defer dbh.Close()
+
+		tx, err := eql.SqlBegin()
+		SoMsg("sql begin err", err, ShouldBeNil)
+		defer tx.Rollback()
+
+		wid, err := tx.Insert("word", "f", "fresh", "fresh")
+		SoMsg("insert word error", err, ShouldBeNil)
+
+		statement := `LOOKUP word.ID WITHIN word.Word == {1}`
+		_, results, err := tx.Perform(statement, "fresh")
+		SoMsg("tx perform error", err, ShouldBeNil)
+		SoMsg("tx perform results", results, ShouldHaveLength, 1)
+		SoMsg("tx perform id", results[0]["id"], ShouldEqual, wid)
+		_, results, err = tx.TX().Perform(statement, "fresh")
+		SoMsg("child tx perform error", err, ShouldBeNil)
+		SoMsg("child tx perform results", results, ShouldHaveLength, 1)
+
+		query, argv, err := tx.ToSQL(statement, "fresh")
+		SoMsg("tx to sql error", err, ShouldBeNil)
+		eqlQuery, eqlArgv, _ := eql.ToSQL(statement, "fresh")
+		SoMsg("tx to sql", query, ShouldEqual, eqlQuery)
+		SoMsg("tx to sql argv", argv, ShouldEqual, eqlArgv)
+		brief, _, err := tx.Plan(statement, "fresh")
+		SoMsg("tx plan error", err, ShouldBeNil)
+		eqlBrief, _, _ := eql.Plan(statement, "fresh")
+		SoMsg("tx plan", brief, ShouldEqual, eqlBrief)
+
+		pid, err := tx.Insert("page", "0123456789", "en", "page", "", nil, nil, "/fresh")
+		SoMsg("insert page error", err, ShouldBeNil)
+		_, err = tx.Insert("page_words", pid, wid, 1)
+		SoMsg("insert page words error", err, ShouldBeNil)
+
+		_, results, next, err := tx.PerformPage("", `LOOKUP word.Word WITHIN word.Word == {1} ORDER BY word.Word LIMIT 1`, "fresh")
+		SoMsg("tx perform page error", err, ShouldBeNil)
+		SoMsg("tx perform page results", results, ShouldHaveLength, 1)
+		SoMsg("tx perform page next", next, ShouldEqual, "")
+		paginated, err := tx.Paginate(statement, 1, 10, "fresh")
+		SoMsg("tx paginate error", err, ShouldBeNil)
+		SoMsg("tx paginate total", paginated.Total, ShouldEqual, 1)
+		SoMsg("tx paginate results", paginated.Results, ShouldHaveLength, 1)
+		facets, err := tx.Facets(`page.Url == {1}`, []string{"word.Word"}, "/fresh")
+		SoMsg("tx facets error", err, ShouldBeNil)
+		SoMsg("tx facets", facets, ShouldHaveLength, 1)
+		SoMsg("tx facets values", facets[0].Values, ShouldEqual, []*FacetValue{{Value: "fresh", Count: 1}})
+		facets, err = eql.Facets(`page.Url == {1}`, []string{"word.Word"}, "/fresh")
+		SoMsg("eql facets error", err, ShouldBeNil)
+		SoMsg("eql facets values", facets[0].Values, ShouldBeEmpty)
+
+		_, _, err = tx.Perform(`LOOKUP nope.ID`)
+		SoMsg("tx perform invalid", err, ShouldNotBeNil)
+
+		SoMsg("sql rollback err", tx.Rollback(), ShouldBeNil)
+		SoMsg("rolled back", hasWord(eql, "fresh"), ShouldEqual, 0)
+
+	})
+}
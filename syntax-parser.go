@@ -17,6 +17,7 @@ package enjinql
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -48,10 +49,10 @@ const (
 	glInt            = `\b(\d+)\b`
 	glFloat          = `\b(\d*\.\d+)\b`
 	glIdent          = `\b([_a-zA-Z][_a-zA-Z0-9]*)\b`
-	glOperator       = `(==|\!=|\^=|\$=|\~=|\*=|<=|>=|<>|<|>)`
+	glOperator       = `(\->|==|\!=|\^=|\$=|\~=|\*=|<=|>=|<>|<|>|=)`
 	glEmptySpace     = `\s+`
 	glPlaceholder    = `\{\d+\}`
-	glPunctuation    = `[.,;!()]`
+	glPunctuation    = `[.,;:!()]`
 	glSingleQuoted   = `'(?:\\'|[^'])*'`
 	glDoubleQuoted   = `"(?:\\"|[^"])*"`
 	glBacktickQuoted = "`(?:\\\\`|[^`])*`"
@@ -59,11 +60,13 @@ const (
 
 var (
 	gLexerKeywords = []string{
+		"UNFILTERED",
 		"DISTINCT",
-		"LOOKUP", "OFFSET", "WITHIN", "RANDOM",
-		"QUERY", "COUNT", "FALSE", "ORDER", "LIMIT",
-		"DESC", "LIKE", "TRUE", "NULL",
-		"AND", "ASC", "DSC", "NOT", "NIL",
+		"INCLUDE",
+		"LOOKUP", "OFFSET", "WITHIN", "RANDOM", "FACETS", "INSERT", "VALUES", "UPDATE", "DELETE",
+		"QUERY", "COUNT", "FALSE", "ORDER", "LIMIT", "AFTER", "MONTH", "GROUP",
+		"DESC", "LIKE", "TRUE", "NULL", "YEAR", "INTO", "FROM",
+		"ALL", "AND", "ASC", "DSC", "NOT", "NIL", "PER", "DAY", "SET",
 		"AS", "BY", "IN", "OR",
 		"SW", "EW", "CS", "CF",
 	}
@@ -108,6 +111,22 @@ func ParseSyntax[V []byte | string](input V) (parsed *Syntax, err error) {
 	return
 }
 
+// ParseModify parses the input string and returns a validated Modify tree
+func ParseModify[V []byte | string](input V) (parsed *Modify, err error) {
+	switch t := interface{}(input).(type) {
+	case []byte:
+		parsed, err = gModifyParser.ParseBytes("enjinql", t)
+	case string:
+		parsed, err = gModifyParser.ParseString("enjinql", t)
+	}
+	if parsed != nil && err == nil {
+		if err = parsed.Validate(); err != nil {
+			parsed = nil
+		}
+	}
+	return
+}
+
 var (
 	txPlaceholders = regexp.MustCompile(`(\{\d+\})`)
 )
@@ -133,7 +152,8 @@ func rplPlaceholders(input string, argc int, argv []interface{}) string {
 	for _, placeholder := range scanPlaceholders(input) {
 		if pos, ok := parsePlaceholder(placeholder); ok {
 			if pos > 0 && pos <= argc {
-				if _, ok := argv[pos-1].(string); ok {
+				if argv[pos-1] != nil && reflect.TypeOf(argv[pos-1]).Kind() == reflect.String {
+					// named string types are quoted the same as strings
 					input = strings.Replace(input, placeholder, "%["+strconv.Itoa(pos)+"]q", 1)
 				} else if _, ok := argv[pos-1].(time.Time); ok {
 					input = strings.Replace(input, placeholder, "%["+strconv.Itoa(pos)+"]q", 1)
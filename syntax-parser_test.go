@@ -15,6 +15,7 @@
 package enjinql
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -66,6 +67,35 @@ func TestSyntaxParser(t *testing.T) {
 
 	})
 
+	Convey("modify statements", t, func() {
+
+		for idx, test := range []struct {
+			input  string
+			output string
+			err    bool
+		}{
+			{input: `insert into page (.shasum, .url) values ("1234", 'one');`, output: `INSERT INTO page (.shasum, .url) VALUES ("1234", 'one');`},
+			{input: `INSERT INTO page_words (.page_id, .hits, .flag) VALUES (1, 2, TRUE)`, output: `INSERT INTO page_words (.page_id, .hits, .flag) VALUES (1, 2, TRUE)`},
+			{input: `UPDATE page SET .type = "post", .archetype = NIL WITHIN .type == "blog"`, output: `UPDATE page SET .type = "post", .archetype = NULL WITHIN .type == "blog"`},
+			{input: `delete from page_words within (word.word == "quote") and (.hits < 2)`, output: `DELETE FROM page_words WITHIN (word.word == "quote") AND (.hits < 2)`},
+			{input: `INSERT INTO page (.shasum, .url) VALUES ("1234")`, err: true},
+			{input: `INSERT INTO page (.shasum) VALUES (.url)`, err: true},
+			{input: `UPDATE page SET .type = "post"`, err: true},
+			{input: `UPDATE page SET .type = .url WITHIN .type == "blog"`, err: true},
+			{input: `DELETE FROM page`, err: true},
+			{input: `LOOKUP .url`, err: true},
+		} {
+			parsed, err := ParseModify(test.input)
+			if test.err {
+				SoMsg(fmt.Sprintf("modify #%d error", idx), err, ShouldNotBeNil)
+				continue
+			}
+			SoMsg(fmt.Sprintf("modify #%d error", idx), err, ShouldBeNil)
+			SoMsg(fmt.Sprintf("modify #%d output", idx), parsed.String(), ShouldEqual, test.output)
+		}
+
+	})
+
 	Convey("testdata/syntax", t, func() {
 
 		batch := func(prefix string, a hrx.Archive) {
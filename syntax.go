@@ -22,25 +22,38 @@ import (
 )
 
 type Syntax struct {
-	Lookup    bool         `parser:" ( ( @'LOOKUP'        " json:"lookup,omitempty"`
-	Count     bool         `parser:"     @'COUNT'?        " json:"count,omitempty"`
-	Distinct  bool         `parser:"     @'DISTINCT'?     " json:"distinct,omitempty"`
-	Keys      []*SourceKey `parser:"     @@ ( ',' @@ )* ) " json:"keys,omitempty"`
-	Query     bool         `parser:"   | @'QUERY' )       " json:"query,omitempty"`
-	Within    *Expression  `parser:" ( 'WITHIN' @@ )?     " json:"within,omitempty"`
-	OrderBy   *OrderBy     `parser:" ( @@ )?              " json:"orderBy,omitempty"`
-	Offset    *int         `parser:" ( 'OFFSET' @Int )?   " json:"offset,omitempty"`
-	Limit     *int         `parser:" ( 'LIMIT' @Int )?    " json:"limit,omitempty"`
-	Semicolon bool         `parser:" ( @';' )?            " json:"semicolon,omitempty"`
+	Lookup     bool          `parser:" (   @'LOOKUP'                       " json:"lookup,omitempty"`
+	Query      bool          `parser:"   | @'QUERY'                        " json:"query,omitempty"`
+	Facets     bool          `parser:"   | @'FACETS' )                     " json:"facets,omitempty"`
+	Unfiltered bool          `parser:" @'UNFILTERED'?                      " json:"unfiltered,omitempty"`
+	All        bool          `parser:" @'ALL'?                             " json:"all,omitempty"`
+	Count      bool          `parser:" @'COUNT'?                           " json:"count,omitempty"`
+	Distinct   bool          `parser:" @'DISTINCT'?                        " json:"distinct,omitempty"`
+	Keys       []*SourceKey  `parser:" ( @@ ( ',' @@ )* )?                 " json:"keys,omitempty"`
+	Within     *Expression   `parser:" ( 'WITHIN' @@ )?                    " json:"within,omitempty"`
+	Include    []*SourceKey  `parser:" ( 'INCLUDE' @@ ( ',' @@ )* )?      " json:"include,omitempty"`
+	GroupBy    *[]*SourceRef `parser:" ( 'GROUP' 'BY' @@ ( ',' @@ )* )?    " json:"groupBy,omitempty"`
+	OrderBy    *OrderBy      `parser:" ( @@ )?                             " json:"orderBy,omitempty"`
+	After      *Value        `parser:" ( 'AFTER' @@ )?                     " json:"after,omitempty"`
+	Offset     *int          `parser:" ( 'OFFSET' @Int )?                  " json:"offset,omitempty"`
+	Limit      *int          `parser:" ( 'LIMIT' @Int                      " json:"limit,omitempty"`
+	Per        *SourceRef    `parser:"   ( 'PER' @@ )? )?                  " json:"per,omitempty"`
+	Semicolon  bool          `parser:" ( @';' )?                           " json:"semicolon,omitempty"`
 
 	Pos lexer.Position
+
+	// maxRows limits all of the rows selected by statements using LIMIT n
+	// PER <key>, see Policy.MaxLimit, and skipRows offsets them when maxRows
+	// is not nil, see Paginate
+	maxRows  *int
+	skipRows *int
 }
 
 func (s *Syntax) init() (err error) {
 	switch {
 	case s.Lookup:
 	case s.Count:
-		if len(s.Keys) != 1 {
+		if len(s.Keys) != 1 && s.GroupBy == nil {
 			err = fmt.Errorf("%w: COUNT requires exactly one context key", ErrInvalidSyntax)
 			return
 		}
@@ -50,6 +63,7 @@ func (s *Syntax) init() (err error) {
 			return
 		}
 	case s.Query:
+	case s.Facets:
 	default:
 		if len(s.Keys) > 0 {
 			s.Lookup = true
@@ -71,6 +85,8 @@ func (s *Syntax) String() string {
 			out += "QUERY"
 		case s.Lookup:
 			out += "LOOKUP"
+		case s.Facets:
+			out += "FACETS"
 		default:
 			if len(s.Keys) > 0 {
 				out += "LOOKUP"
@@ -79,7 +95,15 @@ func (s *Syntax) String() string {
 			}
 		}
 
-		if s.Lookup {
+		if s.Unfiltered {
+			out += " UNFILTERED"
+		}
+
+		if s.All {
+			out += " ALL"
+		}
+
+		if s.Lookup || s.Facets {
 
 			if s.Count {
 				out += " COUNT"
@@ -102,16 +126,43 @@ func (s *Syntax) String() string {
 			out += " WITHIN " + s.Within.String()
 		}
 
+		if len(s.Include) > 0 {
+			out += " INCLUDE"
+			for idx, sk := range s.Include {
+				if idx > 0 {
+					out += ","
+				}
+				out += " " + sk.String()
+			}
+		}
+
+		if s.GroupBy != nil {
+			out += " GROUP BY"
+			for idx, ref := range *s.GroupBy {
+				if idx > 0 {
+					out += ","
+				}
+				out += " " + ref.String()
+			}
+		}
+
 		if s.OrderBy != nil {
 			out += " " + s.OrderBy.String()
 		}
 
+		if s.After != nil {
+			out += " AFTER " + s.After.String()
+		}
+
 		if s.Offset != nil {
-			out += " " + strconv.Itoa(*s.Offset)
+			out += " OFFSET " + strconv.Itoa(*s.Offset)
 		}
 
 		if s.Limit != nil {
-			out += " " + strconv.Itoa(*s.Limit)
+			out += " LIMIT " + strconv.Itoa(*s.Limit)
+			if s.Per != nil {
+				out += " PER " + s.Per.String()
+			}
 		}
 
 		if s.Semicolon {
@@ -128,12 +179,14 @@ func (s *Syntax) Validate() (err error) {
 	if s.Query {
 		if numKeys > 0 {
 			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrMismatchQuery)
+		} else if s.Count || s.Distinct {
+			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrMismatchQueryCount)
 		}
 	} else if s.Lookup {
 		if numKeys == 0 {
 			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrMismatchLookup)
 		} else if s.Count {
-			if numKeys != 1 {
+			if numKeys != 1 && s.GroupBy == nil {
 				err = fmt.Errorf("%w: COUNT requires exactly one source key", ErrInvalidSyntax)
 				return
 			}
@@ -143,6 +196,16 @@ func (s *Syntax) Validate() (err error) {
 				return
 			}
 		}
+	} else if s.Facets {
+		if numKeys == 0 {
+			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrMismatchFacets)
+		} else if s.All || s.Count || s.Distinct || s.GroupBy != nil || s.OrderBy != nil || s.After != nil || s.Offset != nil || s.Per != nil {
+			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrFacetsClauses)
+		}
+	}
+
+	if s.All && s.Distinct {
+		return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrAllDistinct)
 	}
 
 	for _, sk := range s.Keys {
@@ -151,18 +214,64 @@ func (s *Syntax) Validate() (err error) {
 		}
 	}
 
+	if len(s.Include) > 0 {
+		if !s.Query {
+			return newSyntaxError(s.Include[0].Pos, ErrInvalidSyntax, ErrIncludeQuery)
+		}
+		for _, sk := range s.Include {
+			if err = sk.validate(); err != nil {
+				return
+			}
+		}
+	}
+
 	if s.Within != nil {
 		if err = s.Within.validate(); err != nil {
 			return
 		}
 	}
 
+	if s.GroupBy != nil {
+		if !s.Lookup {
+			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrGroupByLookup)
+		} else if s.All || s.Distinct || s.After != nil || s.Per != nil {
+			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrGroupByClauses)
+		}
+		for _, ref := range *s.GroupBy {
+			if err = ref.validate(); err != nil {
+				return
+			}
+		}
+	}
+
 	if s.OrderBy != nil {
 		if err = s.OrderBy.validate(); err != nil {
 			return
 		}
 	}
 
+	if s.After != nil {
+		if s.After.Text == nil && s.After.Placeholder == nil {
+			return newSyntaxError(s.After.Pos, ErrInvalidSyntax, ErrAfterValue)
+		} else if s.Count || s.Distinct {
+			return newSyntaxError(s.After.Pos, ErrInvalidSyntax, ErrAfterCount)
+		} else if s.OrderBy != nil && s.OrderBy.IsRandom() && s.OrderBy.Seed == nil {
+			return newSyntaxError(s.After.Pos, ErrInvalidSyntax, ErrAfterRandom)
+		}
+	}
+
+	if s.Per != nil {
+		if s.Limit == nil {
+			return newSyntaxError(s.Per.Pos, ErrInvalidSyntax, ErrPerLimit)
+		} else if s.Count || s.Distinct || s.After != nil || s.Offset != nil {
+			return newSyntaxError(s.Per.Pos, ErrInvalidSyntax, ErrPerClauses)
+		} else if s.OrderBy != nil && s.OrderBy.IsRandom() && s.OrderBy.Seed == nil {
+			return newSyntaxError(s.Per.Pos, ErrInvalidSyntax, ErrPerRandom)
+		} else if err = s.Per.validate(); err != nil {
+			return
+		}
+	}
+
 	if s.Offset != nil {
 		if *s.Offset < 0 {
 			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrNegativeOffset)
@@ -185,9 +294,17 @@ func (s *Syntax) findSources() (sources []*SrcKey) {
 	if s.Within != nil {
 		sources = append(sources, s.Within.findSources()...)
 	}
+	if s.GroupBy != nil {
+		for _, ref := range *s.GroupBy {
+			sources = append(sources, ref.findSources()...)
+		}
+	}
 	if s.OrderBy != nil {
 		sources = append(sources, s.OrderBy.findSources()...)
 	}
+	if s.Per != nil {
+		sources = append(sources, s.Per.findSources()...)
+	}
 	return
 }
 
@@ -198,15 +315,28 @@ func (s *Syntax) findUpdatedSources() (sources []*SrcKey) {
 	if s.Within != nil {
 		sources = append(sources, s.Within.findSources()...)
 	}
+	if s.GroupBy != nil {
+		for _, ref := range *s.GroupBy {
+			sources = append(sources, ref.findSources()...)
+		}
+	}
 	if s.OrderBy != nil {
 		sources = append(sources, s.OrderBy.findSources()...)
 	}
+	if s.Per != nil {
+		sources = append(sources, s.Per.findSources()...)
+	}
 	return
 }
 
 func (s *Syntax) apply(argv ...interface{}) (err error) {
 	if s.Within != nil {
-		err = s.Within.apply(argv...)
+		if err = s.Within.apply(argv...); err != nil {
+			return
+		}
+	}
+	if s.After != nil {
+		err = s.After.apply(argv...)
 	}
 	return
 }
@@ -87,6 +87,15 @@ func (c *Condition) apply(argv ...interface{}) (err error) {
 	return
 }
 
+func (c *Condition) qualify(source string) {
+	if c.Left != nil {
+		c.Left.qualify(source)
+	}
+	if c.Right != nil {
+		c.Right.qualify(source)
+	}
+}
+
 func (c *Condition) String() (out string) {
 	if c.validate() == nil {
 		out += "(" + c.Left.String() + ")"
@@ -83,6 +83,18 @@ func (c *Constraint) apply(argv ...interface{}) (err error) {
 	return
 }
 
+func (c *Constraint) qualify(source string) {
+	if c.Left != nil {
+		c.Left.qualify(source)
+	}
+	if c.Right != nil {
+		c.Right.qualify(source)
+	}
+	for _, value := range c.Values {
+		value.qualify(source)
+	}
+}
+
 func (c *Constraint) String() (out string) {
 	if c.validate() == nil {
 		out += c.Left.String()
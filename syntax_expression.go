@@ -80,6 +80,17 @@ func (e *Expression) apply(argv ...interface{}) (err error) {
 	return
 }
 
+// qualify sets the source of all source references without one to the given
+// source name
+func (e *Expression) qualify(source string) {
+	switch {
+	case e.Condition != nil:
+		e.Condition.qualify(source)
+	case e.Constraint != nil:
+		e.Constraint.qualify(source)
+	}
+}
+
 func (e *Expression) String() (out string) {
 	switch {
 	case e.Condition != nil:
@@ -0,0 +1,94 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+
+	clStrings "github.com/go-corelibs/strings"
+)
+
+var (
+	// rxJsonPath matches the supported JSON paths, which are restricted to
+	// simple field names and array indexes
+	rxJsonPath = regexp.MustCompile(`^\$(?:\.[_a-zA-Z][_a-zA-Z0-9]*|\[\d+\])*$`)
+	// rxJsonPathStep matches each field name or array index of a JSON path
+	rxJsonPathStep = regexp.MustCompile(`\.([_a-zA-Z][_a-zA-Z0-9]*)|\[(\d+)\]`)
+)
+
+// JsonPath is the extraction of a value from a string source key holding a
+// JSON document, for example:
+//
+//	.stub->"$.title"
+//	.stub.title
+type JsonPath struct {
+	Quoted *string   `parser:" (   '->' @String     " json:"quoted,omitempty"`
+	Fields *[]string `parser:"   | ( '.' @( Ident | Keyword ) )+ ) " json:"fields,omitempty"`
+
+	Pos lexer.Position
+}
+
+func (j *JsonPath) validate() (err error) {
+	if j.Quoted == nil && (j.Fields == nil || len(*j.Fields) == 0) {
+		return newSyntaxError(j.Pos, ErrInvalidSyntax, ErrNilStructure)
+	} else if !rxJsonPath.MatchString(j.path()) {
+		return newSyntaxError(j.Pos, ErrInvalidSyntax, ErrJsonPath)
+	}
+	return
+}
+
+// path returns the JSON path in the "$.field[0]" form
+func (j *JsonPath) path() (path string) {
+	if j.Quoted != nil {
+		return clStrings.TrimQuotes(*j.Quoted)
+	} else if j.Fields != nil {
+		return "$." + strings.Join(*j.Fields, ".")
+	}
+	return
+}
+
+// steps returns the field names and array indexes of the JSON path
+func (j *JsonPath) steps() (steps []interface{}) {
+	for _, m := range rxJsonPathStep.FindAllStringSubmatch(j.path(), -1) {
+		if m[1] != "" {
+			steps = append(steps, m[1])
+		} else if idx, err := strconv.Atoi(m[2]); err == nil {
+			steps = append(steps, idx)
+		}
+	}
+	return
+}
+
+// name returns the last field name of the JSON path, if there is one
+func (j *JsonPath) name() (name string) {
+	steps := j.steps()
+	if last := len(steps) - 1; last >= 0 {
+		name, _ = steps[last].(string)
+	}
+	return
+}
+
+func (j *JsonPath) String() string {
+	if j.Quoted != nil {
+		return "->" + *j.Quoted
+	} else if j.Fields != nil {
+		return "." + strings.Join(*j.Fields, ".")
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+var (
+	gModifyParser = participle.MustBuild[Modify](
+		participle.Lexer(gSyntaxLexer),
+		participle.CaseInsensitive("Keyword"),
+	)
+)
+
+// Modify is an EQL data-modification statement, one of:
+//
+//	INSERT INTO <source> (.<key>, ...) VALUES (<value>, ...)
+//	UPDATE <source> SET .<key> = <value>, ... WITHIN <expression>
+//	DELETE FROM <source> WITHIN <expression>
+//
+// Modify statements are performed with SqlTX.Execute
+type Modify struct {
+	Insert    *Insert `parser:" (   @@        " json:"insert,omitempty"`
+	Update    *Update `parser:"   | @@        " json:"update,omitempty"`
+	Delete    *Delete `parser:"   | @@ )      " json:"delete,omitempty"`
+	Semicolon bool    `parser:" ( @';' )?     " json:"semicolon,omitempty"`
+
+	Pos lexer.Position
+}
+
+// Insert is the INSERT INTO statement
+type Insert struct {
+	Source string   `parser:" 'INSERT' 'INTO' @Ident                   " json:"source"`
+	Keys   []string `parser:" '(' '.' @( Ident | Keyword ) ( ',' '.' @( Ident | Keyword ) )* ')' " json:"keys"`
+	Values []*Value `parser:" 'VALUES' '(' @@ ( ',' @@ )* ')'           " json:"values"`
+
+	Pos lexer.Position
+}
+
+// Update is the UPDATE SET statement
+type Update struct {
+	Source string        `parser:" 'UPDATE' @Ident               " json:"source"`
+	Set    []*Assignment `parser:" 'SET' @@ ( ',' @@ )*          " json:"set"`
+	Within *Expression   `parser:" 'WITHIN' @@                   " json:"within"`
+
+	Pos lexer.Position
+}
+
+// Assignment is one UPDATE SET source key value
+type Assignment struct {
+	Key   string `parser:" '.' @( Ident | Keyword ) " json:"key"`
+	Value *Value `parser:" '=' @@     " json:"value"`
+
+	Pos lexer.Position
+}
+
+// Delete is the DELETE FROM statement
+type Delete struct {
+	Source string      `parser:" 'DELETE' 'FROM' @Ident " json:"source"`
+	Within *Expression `parser:" 'WITHIN' @@             " json:"within"`
+
+	Pos lexer.Position
+}
+
+func (m *Modify) String() (out string) {
+	if m.Validate() == nil {
+		switch {
+		case m.Insert != nil:
+			out = m.Insert.String()
+		case m.Update != nil:
+			out = m.Update.String()
+		case m.Delete != nil:
+			out = m.Delete.String()
+		}
+		if m.Semicolon {
+			out += ";"
+		}
+	}
+	return
+}
+
+func (m *Modify) Validate() (err error) {
+	switch {
+	case m.Insert != nil:
+		return m.Insert.validate()
+	case m.Update != nil:
+		return m.Update.validate()
+	case m.Delete != nil:
+		return m.Delete.validate()
+	}
+	return newSyntaxError(m.Pos, ErrInvalidSyntax, ErrNilStructure)
+}
+
+func (i *Insert) String() (out string) {
+	out = "INSERT INTO " + i.Source + " ("
+	for idx, key := range i.Keys {
+		if idx > 0 {
+			out += ", "
+		}
+		out += "." + key
+	}
+	out += ") VALUES ("
+	for idx, value := range i.Values {
+		if idx > 0 {
+			out += ", "
+		}
+		out += value.String()
+	}
+	return out + ")"
+}
+
+func (i *Insert) validate() (err error) {
+	if len(i.Keys) != len(i.Values) {
+		return newSyntaxError(i.Pos, ErrInvalidSyntax, ErrInsertValues)
+	}
+	for _, value := range i.Values {
+		if err = value.validate(); err != nil {
+			return
+		} else if value.SourceRef != nil {
+			return newSyntaxError(value.Pos, ErrInvalidSyntax, ErrModifyValue)
+		}
+	}
+	return
+}
+
+func (u *Update) String() (out string) {
+	out = "UPDATE " + u.Source + " SET "
+	for idx, set := range u.Set {
+		if idx > 0 {
+			out += ", "
+		}
+		out += "." + set.Key + " = " + set.Value.String()
+	}
+	return out + " WITHIN " + u.Within.String()
+}
+
+func (u *Update) validate() (err error) {
+	for _, set := range u.Set {
+		if set.Value == nil {
+			return newSyntaxError(set.Pos, ErrInvalidSyntax, ErrMissingRightSide)
+		} else if err = set.Value.validate(); err != nil {
+			return
+		} else if set.Value.SourceRef != nil {
+			return newSyntaxError(set.Value.Pos, ErrInvalidSyntax, ErrModifyValue)
+		}
+	}
+	if u.Within == nil {
+		return newSyntaxError(u.Pos, ErrInvalidSyntax, ErrModifyWithin)
+	}
+	return u.Within.validate()
+}
+
+func (d *Delete) String() (out string) {
+	return "DELETE FROM " + d.Source + " WITHIN " + d.Within.String()
+}
+
+func (d *Delete) validate() (err error) {
+	if d.Within == nil {
+		return newSyntaxError(d.Pos, ErrInvalidSyntax, ErrModifyWithin)
+	}
+	return d.Within.validate()
+}
+
+// makeValue returns the Go value of an INSERT or UPDATE value
+func (m *Modify) makeValue(v *Value) (value interface{}, err error) {
+	switch {
+	case v.Bool != nil:
+		value = bool(*v.Bool)
+	case v.Null != nil:
+		value = nil
+	default:
+		value, err = v.makeOther(nil)
+	}
+	return
+}
@@ -15,6 +15,9 @@
 package enjinql
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/alecthomas/participle/v2/lexer"
@@ -22,10 +25,36 @@ import (
 	"github.com/go-corelibs/go-sqlbuilder"
 )
 
+const (
+	// gRandomMarker prefixes the placeholder function names used for seeded
+	// RANDOM() clauses, see expandRandom
+	gRandomMarker = "EQL_RANDOM_"
+	// gRandomModulus is the prime keeping every step of the seeded RANDOM()
+	// hash below 2^31, so that no product can overflow 64-bit integers
+	gRandomModulus = 2147483647
+	// gRandomMultiplier and gRandomMixer are the odd constants multiplying
+	// each step of the seeded RANDOM() hash
+	gRandomMultiplier = 1597334677
+	gRandomMixer      = 1013904223
+)
+
+var (
+	// gRandomXorFormats are the dialect specific bitwise XOR expressions,
+	// SQLite does not have an XOR operator
+	gRandomXorFormats = map[string]string{
+		"sqlite3":    `((%[1]s | %[2]s) - (%[1]s & %[2]s))`,
+		"postgresql": `(%[1]s # %[2]s)`,
+		"mysql":      `(%[1]s ^ %[2]s)`,
+	}
+
+	gRandomPattern = regexp.MustCompile(gRandomMarker + `(\d+)\(([^()]*)\)`)
+)
+
 type OrderBy struct {
-	Sources   *[]*SourceRef `parser:" 'ORDER' 'BY' (   @@ ( ',' @@ )*          " json:"key"`
-	Random    *bool         `parser:"                | @( 'RANDOM' '(' ')' ) ) " json:"random,omitempty"`
-	Direction *string       `parser:" @( 'ASC' | 'DSC' | 'DESC' )?             " json:"dir,omitempty"`
+	Sources   *[]*SourceRef `parser:" 'ORDER' 'BY' (   @@ ( ',' @@ )*           " json:"key"`
+	Random    *bool         `parser:"                | @'RANDOM' '('            " json:"random,omitempty"`
+	Seed      *int          `parser:"                  @Int? ')' )              " json:"seed,omitempty"`
+	Direction *string       `parser:" @( 'ASC' | 'DSC' | 'DESC' )?              " json:"dir,omitempty"`
 
 	Pos lexer.Position
 }
@@ -34,14 +63,48 @@ func (o *OrderBy) IsDESC() bool {
 	return o.Direction != nil && strings.ToUpper(*o.Direction) != "ASC"
 }
 
+// IsRandom returns true if this is an ORDER BY RANDOM() clause, seeded or not
+func (o *OrderBy) IsRandom() bool {
+	return o.Random != nil && *o.Random
+}
+
+// IsSeeded returns true if this is an ORDER BY RANDOM(seed) clause
+func (o *OrderBy) IsSeeded() bool {
+	return o.IsRandom() && o.Seed != nil
+}
+
 func (o *OrderBy) make(state *cProcessor) (err error) {
 	if err = o.validate(); err != nil {
 		return
+	} else if o.IsRandom() && !o.IsSeeded() && state.wrap != nil {
+		// random values are never distinct, order the outer query instead
+		state.wrap.random = true
+		return
 	}
 	var columns []sqlbuilder.Column
-	if o.Random != nil && *o.Random {
-		columns = append(columns, sqlbuilder.Func("RANDOM"))
-	} else {
+	if columns, err = o.makeColumns(state); err != nil {
+		return
+	}
+	if o.IsSeeded() {
+		// ids hashed to the same value are ordered by id
+		var t sqlbuilder.Table
+		if t, err = state.getKeySources()[0].getTable(); err != nil {
+			return
+		}
+		columns = append(columns, t.C(SourceIdKey))
+	}
+	state.orderBy(o.IsDESC(), columns...)
+	return
+}
+
+func (o *OrderBy) makeColumns(state *cProcessor) (columns []sqlbuilder.Column, err error) {
+	if o.IsRandom() {
+		var column sqlbuilder.Column
+		if column, err = o.makeRandom(state); err != nil {
+			return
+		}
+		columns = append(columns, column)
+	} else if o.Sources != nil {
 		for _, srcRef := range *o.Sources {
 			var column sqlbuilder.Column
 			if column, err = srcRef.make(state); err != nil {
@@ -50,7 +113,64 @@ func (o *OrderBy) make(state *cProcessor) (err error) {
 			columns = append(columns, column)
 		}
 	}
-	state.build.OrderBy(o.IsDESC(), columns...)
+	return
+}
+
+// getRandomFunc returns the name of the dialect specific random function
+func getRandomFunc(dialect sqlbuilder.Dialect) (name string) {
+	if dialect.Name() == "mysql" {
+		return "RAND"
+	}
+	return "RANDOM"
+}
+
+// makeRandom returns the dialect specific random function when there is no
+// seed given, otherwise returns a placeholder function hashing the id of the
+// first key source with the seed, which is a stable shuffle for each seed
+func (o *OrderBy) makeRandom(state *cProcessor) (column sqlbuilder.Column, err error) {
+	if o.Seed == nil {
+		column = sqlbuilder.Func(getRandomFunc(state.builder.Dialect()))
+		return
+	}
+
+	sources := state.getKeySources()
+	if len(sources) == 0 {
+		err = newSyntaxError(o.Pos, ErrInvalidSyntax, ErrMissingSourceKey)
+		return
+	}
+	var t sqlbuilder.Table
+	if t, err = sources[0].getTable(); err != nil {
+		return
+	}
+
+	seed := *o.Seed % gRandomModulus
+	if seed < 0 {
+		seed += gRandomModulus
+	}
+	column = sqlbuilder.Func(gRandomMarker+strconv.Itoa(seed), t.C(SourceIdKey))
+	return
+}
+
+// expandRandom replaces all seeded RANDOM() placeholders in the query with
+// the dialect specific SQL hashing the id with the seed:
+//
+//	h = ((id XOR seed) % M) * A % M
+//	h = (h XOR (h >> 15)) * B % M
+func expandRandom(dialect sqlbuilder.Dialect, query string) (expanded string, err error) {
+	if !strings.Contains(query, gRandomMarker) {
+		return query, nil
+	}
+	xor, ok := gRandomXorFormats[dialect.Name()]
+	if !ok {
+		err = fmt.Errorf("%w: %q", ErrRandomDialect, dialect.Name())
+		return
+	}
+	modulus := strconv.Itoa(gRandomModulus)
+	expanded = gRandomPattern.ReplaceAllStringFunc(query, func(match string) string {
+		m := gRandomPattern.FindStringSubmatch(match)
+		h := "((" + fmt.Sprintf(xor, m[2], m[1]) + " % " + modulus + ") * " + strconv.Itoa(gRandomMultiplier) + " % " + modulus + ")"
+		return "((" + fmt.Sprintf(xor, h, "("+h+" >> 15)") + " * " + strconv.Itoa(gRandomMixer) + ") % " + modulus + ")"
+	})
 	return
 }
 
@@ -75,8 +195,12 @@ func (o *OrderBy) findSources() (names []*SrcKey) {
 }
 
 func (o *OrderBy) String() (out string) {
-	if o.Random != nil && *o.Random {
-		out += "RANDOM()"
+	if o.IsRandom() {
+		out += "RANDOM("
+		if o.Seed != nil {
+			out += strconv.Itoa(*o.Seed)
+		}
+		out += ")"
 	} else if o.Sources != nil {
 		for _, expr := range *o.Sources {
 			out += expr.String()
@@ -0,0 +1,73 @@
+// Copyright (c) 2024  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enjinql
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/go-corelibs/go-sqlbuilder/dialects"
+	"github.com/go-corelibs/testdb"
+)
+
+func TestSeededRandom(t *testing.T) {
+	Convey("seeded random", t, func() {
+
+		eql, _ := makeQfEQL()
+		defer eql.Close()
+
+		_, first, err := eql.Perform(`LOOKUP .Shasum WITHIN word.Word == "quote" ORDER BY RANDOM({1})`, 20261018)
+		SoMsg("first shuffle error", err, ShouldBeNil)
+		SoMsg("first shuffle length", len(first), ShouldEqual, 2)
+		for idx := 0; idx < 5; idx++ {
+			_, again, err := eql.Perform(`LOOKUP .Shasum WITHIN word.Word == "quote" ORDER BY RANDOM({1})`, 20261018)
+			SoMsg("same shuffle error", err, ShouldBeNil)
+			SoMsg("same shuffle results", again, ShouldEqual, first)
+		}
+
+		_, one, err := eql.Perform(`LOOKUP word.Word ORDER BY RANDOM(1)`)
+		SoMsg("seed one error", err, ShouldBeNil)
+		_, two, err := eql.Perform(`LOOKUP word.Word ORDER BY RANDOM(2)`)
+		SoMsg("seed two error", err, ShouldBeNil)
+		_, ordered, err := eql.Perform(`LOOKUP word.Word ORDER BY word.ID`)
+		SoMsg("ordered error", err, ShouldBeNil)
+		SoMsg("seed lengths", len(one), ShouldEqual, len(ordered))
+		SoMsg("seed lengths", len(two), ShouldEqual, len(ordered))
+		SoMsg("different seeds", one, ShouldNotEqual, two)
+		SoMsg("seed one shuffled", one, ShouldNotEqual, ordered)
+		SoMsg("seed two shuffled", two, ShouldNotEqual, ordered)
+
+		_, page, next, err := eql.PerformPage("", `LOOKUP .Shasum ORDER BY RANDOM(7) LIMIT 1`)
+		SoMsg("random first page error", err, ShouldBeNil)
+		_, rest, _, err := eql.PerformPage(next, `LOOKUP .Shasum ORDER BY RANDOM(7) LIMIT 1`)
+		SoMsg("random next page error", err, ShouldBeNil)
+		SoMsg("random pages differ", rest, ShouldNotEqual, page)
+
+		tdb, err := testdb.NewTestDB()
+		SoMsg("mysql test db error", err, ShouldBeNil)
+		defer tdb.Close()
+		mysql, err := New(makeBeConfig(), tdb.DBH(), dialects.MySql{}, SkipCreateTable, SkipCreateIndex)
+		SoMsg("mysql enjinql error", err, ShouldBeNil)
+		query, _, err := mysql.ToSQL(`QUERY ORDER BY RANDOM()`)
+		SoMsg("mysql random error", err, ShouldBeNil)
+		SoMsg("mysql random query", query, ShouldEqual, "SELECT `be_eql_page`.`stub` FROM `be_eql_page` ORDER BY RAND() ASC;")
+		query, _, err = mysql.ToSQL(`QUERY ORDER BY RANDOM(7)`)
+		SoMsg("mysql seeded error", err, ShouldBeNil)
+		SoMsg("mysql seeded xor", query, ShouldContainSubstring, "(`be_eql_page`.`id` ^ 7)")
+		SoMsg("mysql seeded tie-breaker", query, ShouldEndWith, " ASC, `be_eql_page`.`id` ASC;")
+
+	})
+}
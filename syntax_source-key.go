@@ -15,23 +15,63 @@
 package enjinql
 
 import (
+	"strings"
+
 	"github.com/alecthomas/participle/v2/lexer"
 )
 
+// SourceKey is a selected source key, for example: page.url or MONTH(.created)
+//
+// Key names may be keywords, such as .year or page.group, while namespace,
+// source and alias names may not
 type SourceKey struct {
-	Source *string `parser:" ( @Ident (?= '.' ) )? " json:"source,omitempty"`
-	Key    string  `parser:" '.' @Ident            " json:"key"`
-	Alias  *string `parser:" ( 'AS' @Ident )?      " json:"alias,omitempty"`
+	Bucket    *SourceBucket `parser:" (   @@                             " json:"bucket,omitempty"`
+	Namespace *string       `parser:"   | ( @Ident (?= ':' ) ':' )?      " json:"namespace,omitempty"`
+	Source    *string       `parser:"     ( @Ident (?= '.' ) )?          " json:"source,omitempty"`
+	Key       string        `parser:"     '.' @( Ident | Keyword )       " json:"key,omitempty"`
+	Path      *JsonPath     `parser:"     ( @@ )?                      ) " json:"path,omitempty"`
+	Alias     *string       `parser:" ( 'AS' @Ident )?                   " json:"alias,omitempty"`
 
 	Pos lexer.Position
 }
 
+// namespace returns the namespace of this source key, if there is one
+func (s *SourceKey) namespace() *string {
+	if s.Bucket != nil {
+		return s.Bucket.Namespace
+	}
+	return s.Namespace
+}
+
+// srcKey returns the source and key names, and the JSON path, of this source
+// key
+func (s *SourceKey) srcKey() (src *string, key string, path *JsonPath) {
+	if s.Bucket != nil {
+		return s.Bucket.Source, s.Bucket.Key, s.Bucket.Path
+	}
+	return s.Source, s.Key, s.Path
+}
+
+// keyFuncs returns the time bucket name and JSON path of this source key
+func (s *SourceKey) keyFuncs() (bucket *string, path *JsonPath) {
+	if s.Bucket != nil {
+		return &s.Bucket.Name, s.Bucket.Path
+	}
+	return nil, s.Path
+}
+
 func (s *SourceKey) validate() (err error) {
+	source, key, path := s.srcKey()
+	if path != nil {
+		if err = path.validate(); err != nil {
+			return
+		}
+	}
 	if s.Alias == nil {
 		// not an alias, expecting at least key
-		if s.Source == nil && s.Key == "" {
+		if source == nil && key == "" {
 			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrNilStructure)
-		} else if s.Key == "" {
+		} else if key == "" {
 			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrMissingSourceKey)
 		}
 	} else if *s.Alias == "" {
@@ -41,37 +81,63 @@ func (s *SourceKey) validate() (err error) {
 }
 
 func (s *SourceKey) findSources() (names []*SrcKey) {
-	var src, alias string
-	if s.Source != nil {
-		src = *s.Source
-	}
-	if s.Alias != nil {
-		alias = *s.Alias
-	}
-	names = []*SrcKey{newSrcKey(src, s.Key, alias)}
+	names = []*SrcKey{s.AsKey()}
 	return
 }
 
 func (s *SourceKey) AsKey() (sk *SrcKey) {
+	source, key, path := s.srcKey()
 	var src, alias string
-	if s.Source != nil {
-		src = *s.Source
+	if source != nil {
+		src = *source
 	}
 	if s.Alias != nil {
 		alias = *s.Alias
 	}
-	return &SrcKey{
+	sk = &SrcKey{
 		Src:   src,
-		Key:   s.Key,
+		Key:   key,
 		Alias: alias,
 	}
+	if ns := s.namespace(); ns != nil {
+		sk.Namespace = *ns
+	}
+	if path != nil {
+		sk.Path = path.path()
+	}
+	return
+}
+
+// lookup returns the name of this source key within the processor updated
+// source key references
+func (s *SourceKey) lookup() (name string) {
+	if s.Alias != nil {
+		return *s.Alias
+	}
+	return s.name()
+}
+
+// name returns the namespace, source and key names of this source key
+func (s *SourceKey) name() (name string) {
+	if ns := s.namespace(); ns != nil {
+		name += *ns + ":"
+	}
+	source, key, _ := s.srcKey()
+	if source != nil {
+		name += *source
+	}
+	return name + "." + key
 }
 
 func (s *SourceKey) String() (src string) {
-	if s.Source != nil {
-		src += *s.Source
+	_, _, path := s.srcKey()
+	src = s.name()
+	if path != nil {
+		src += path.String()
+	}
+	if s.Bucket != nil {
+		src = strings.ToUpper(s.Bucket.Name) + "(" + src + ")"
 	}
-	src += "." + s.Key
 	if s.Alias != nil {
 		src += " AS " + *s.Alias
 	}
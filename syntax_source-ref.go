@@ -16,6 +16,7 @@ package enjinql
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/alecthomas/participle/v2/lexer"
 
@@ -23,16 +24,55 @@ import (
 )
 
 type SourceRef struct {
-	Source *string `parser:"   ( ( @Ident (?= '.' ) )?     " json:"source,omitempty"`
-	Key    *string `parser:"     '.' @Ident            )   " json:"key,omitempty"`
-	Alias  *string `parser:"   | @Ident                    " json:"alias,omitempty"`
+	Bucket    *SourceBucket `parser:" (   @@                             " json:"bucket,omitempty"`
+	Namespace *string       `parser:"   | ( ( @Ident (?= ':' ) ':' )?    " json:"namespace,omitempty"`
+	Source    *string       `parser:"       ( @Ident (?= '.' ) )?        " json:"source,omitempty"`
+	Key       *string       `parser:"       '.' @( Ident | Keyword )     " json:"key,omitempty"`
+	Path      *JsonPath     `parser:"       ( @@ )?                 )    " json:"path,omitempty"`
+	Alias     *string       `parser:"   | @Ident )                       " json:"alias,omitempty"`
 
 	Pos lexer.Position
 }
 
+// SourceBucket is a time bucket function of a source key, for example:
+// MONTH(.created), used by both SourceKey and SourceRef
+type SourceBucket struct {
+	Name      string    `parser:" @( 'DAY' | 'MONTH' | 'YEAR' ) '('    " json:"name"`
+	Namespace *string   `parser:" ( @Ident (?= ':' ) ':' )?             " json:"namespace,omitempty"`
+	Source    *string   `parser:" ( @Ident (?= '.' ) )?                 " json:"source,omitempty"`
+	Key       string    `parser:" '.' @( Ident | Keyword )              " json:"key"`
+	Path      *JsonPath `parser:" ( @@ )? ')'                           " json:"path,omitempty"`
+
+	Pos lexer.Position
+}
+
+// namespace returns the namespace of this source reference, if there is one
+func (s *SourceRef) namespace() *string {
+	if s.Bucket != nil {
+		return s.Bucket.Namespace
+	}
+	return s.Namespace
+}
+
+// srcKey returns the source and key names, and the JSON path, of this source
+// reference. The source and key names are nil for aliases
+func (s *SourceRef) srcKey() (src, key *string, path *JsonPath) {
+	if s.Bucket != nil {
+		return s.Bucket.Source, &s.Bucket.Key, s.Bucket.Path
+	}
+	return s.Source, s.Key, s.Path
+}
+
 func (s *SourceRef) make(state *cProcessor) (c sqlbuilder.Column, err error) {
-	if u, ok := state.updated[s.String()]; ok {
-		c = u.c
+	if u, ok := state.updated[s.lookup()]; ok {
+		switch {
+		case s.Bucket != nil:
+			c = applyKeyFuncs(&s.Bucket.Name, s.Bucket.Path, u.c)
+		case s.Alias != nil:
+			c = state.applyAlias(*s.Alias, u.c)
+		default:
+			c = applyKeyFuncs(nil, s.Path, u.c)
+		}
 	} else {
 		err = fmt.Errorf("unknown source reference: %q", s.String())
 	}
@@ -40,11 +80,16 @@ func (s *SourceRef) make(state *cProcessor) (c sqlbuilder.Column, err error) {
 }
 
 func (s *SourceRef) validate() (err error) {
+	if _, _, path := s.srcKey(); path != nil {
+		if err = path.validate(); err != nil {
+			return
+		}
+	}
 	if s.Alias == nil {
 		// not an alias, expecting at least key
-		if s.Source == nil && s.Key == nil {
+		if source, key, _ := s.srcKey(); source == nil && (key == nil || *key == "") {
 			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrNilStructure)
-		} else if s.Key == nil {
+		} else if key == nil || *key == "" {
 			return newSyntaxError(s.Pos, ErrInvalidSyntax, ErrMissingSourceKey)
 		}
 	} else if *s.Alias == "" {
@@ -54,30 +99,71 @@ func (s *SourceRef) validate() (err error) {
 }
 
 func (s *SourceRef) findSources() (names []*SrcKey) {
-	if s.Key == nil {
+	source, key, path := s.srcKey()
+	if key == nil {
 		// aliases reference other source instances
 		// missing a key reference is an error
 		return
 	}
 	var src, alias string
-	if s.Source != nil {
-		src = *s.Source
+	if source != nil {
+		src = *source
 	}
 	if s.Alias != nil {
 		alias = *s.Alias
 	}
-	names = []*SrcKey{newSrcKey(src, *s.Key, alias)}
+	names = []*SrcKey{newSrcKey(src, *key, alias)}
+	if ns := s.namespace(); ns != nil {
+		names[0].Namespace = *ns
+	}
+	if path != nil {
+		names[0].Path = path.path()
+	}
 	return
 }
 
-func (s *SourceRef) String() string {
+// qualify sets the source of this source reference to the given source name,
+// unless it already has a source or namespace or is an alias
+func (s *SourceRef) qualify(source string) {
+	switch {
+	case s.Bucket != nil:
+		if s.Bucket.Source == nil && s.Bucket.Namespace == nil {
+			s.Bucket.Source = &source
+		}
+	case s.Key != nil:
+		if s.Source == nil && s.Namespace == nil {
+			s.Source = &source
+		}
+	}
+}
+
+// lookup returns the name of this source reference within the processor
+// updated source key references
+func (s *SourceRef) lookup() string {
+	var prefix string
+	if ns := s.namespace(); ns != nil {
+		prefix = *ns + ":"
+	}
+	source, key, _ := s.srcKey()
 	switch {
 	case s.Alias != nil:
 		return *s.Alias
-	case s.Source != nil && s.Key != nil:
-		return fmt.Sprintf("%s.%s", *s.Source, *s.Key)
-	case s.Source == nil && s.Key != nil:
-		return "." + *s.Key
+	case source != nil && key != nil:
+		return fmt.Sprintf("%s%s.%s", prefix, *source, *key)
+	case source == nil && key != nil:
+		return prefix + "." + *key
 	}
 	return ""
 }
+
+func (s *SourceRef) String() (out string) {
+	_, _, path := s.srcKey()
+	out = s.lookup()
+	if path != nil {
+		out += path.String()
+	}
+	if s.Bucket != nil {
+		out = strings.ToUpper(s.Bucket.Name) + "(" + out + ")"
+	}
+	return
+}
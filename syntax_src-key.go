@@ -15,9 +15,11 @@
 package enjinql
 
 type SrcKey struct {
-	Src   string
-	Key   string
-	Alias string
+	Namespace string
+	Src       string
+	Key       string
+	Alias     string
+	Path      string
 }
 
 func newSrcKey(table, key, alias string) *SrcKey {
@@ -31,6 +33,8 @@ func newSrcKey(table, key, alias string) *SrcKey {
 func (s *SrcKey) String() string {
 	if s.Alias != "" {
 		return s.Alias
+	} else if s.Namespace != "" {
+		return s.Namespace + ":" + s.Src + "." + s.Key
 	} else if s.Src == "" {
 		return "." + s.Key
 	}
@@ -106,6 +106,12 @@ func (v *Value) findSources() (sources []*SrcKey) {
 	return
 }
 
+func (v *Value) qualify(source string) {
+	if v.SourceRef != nil {
+		v.SourceRef.qualify(source)
+	}
+}
+
 func (v *Value) apply(argv ...interface{}) (err error) {
 	if v.Placeholder != nil && *v.Placeholder != "" {
 		var pos int